@@ -0,0 +1,145 @@
+package zysms
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+// ACL 按 IP 的访问控制, 先匹配 deny, allow 不为空时只允许其中的地址
+type ACL struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// NewACL allow/deny 为 CIDR 或单个 IP, 如 10.0.0.0/8、192.168.1.10
+func NewACL(allow, deny []string) (*ACL, error) {
+	a := &ACL{}
+	var err error
+	if a.allow, err = parsePrefixes(allow); err != nil {
+		return nil, err
+	}
+	if a.deny, err = parsePrefixes(deny); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func parsePrefixes(items []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(items))
+	for _, s := range items {
+		s = strings.TrimSpace(s)
+		if strings.Contains(s, "/") {
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("acl 地址错误: %s", s)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("acl 地址错误: %s", s)
+		}
+		ip = ip.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Allowed a 为 nil 时允许所有地址, 无法识别的地址只在 allow 为空时允许
+func (a *ACL) Allowed(addr net.Addr) bool {
+	if a == nil {
+		return true
+	}
+	ip, ok := addrIP(addr)
+	if !ok {
+		return len(a.allow) == 0
+	}
+	for _, p := range a.deny {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	if len(a.allow) == 0 {
+		return true
+	}
+	for _, p := range a.allow {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func addrIP(addr net.Addr) (netip.Addr, bool) {
+	if addr == nil {
+		return netip.Addr{}, false
+	}
+	if a, ok := addr.(*net.TCPAddr); ok {
+		ip, ok := netip.AddrFromSlice(a.IP)
+		return ip.Unmap(), ok
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	ip, err := netip.ParseAddr(host)
+	return ip.Unmap(), err == nil
+}
+
+// allowed 账号设置了 ACL 时校验对端地址
+func (c *sms_conn) allowed(acc *Account) bool {
+	return acc.ACL.Allowed(c.RemoteAddr())
+}
+
+// acl 监听设置的 ACL 优先
+func (l *Listener) acl() *ACL {
+	if l.ACL != nil {
+		return l.ACL
+	}
+	return l.parent.ACL
+}
+
+// deny 监听 ACL 不允许的连接, 读取登录请求并回复与账号 ACL 相同的失败状态后断开
+func (c *sms_conn) deny() {
+	c.logger.Warnf("%v: %s", smserror.ErrAddrNotAllowed, c.RemoteAddr())
+	c.SetReadDeadline(10 * time.Second)
+	if pdu, err := c.action.recv(); err == nil {
+		if resp := denyResp(pdu); resp != nil {
+			c.SendPDU(resp)
+		}
+	}
+	c.close()
+}
+
+// denyResp 登录请求的失败响应: cmpp 5 其他错误, smgp 20 IP地址错, sgip 1 非法登录, smpp ESME_RBINDFAIL
+func denyResp(pdu PDU) PDU {
+	switch p := pdu.(type) {
+	case *cmpp.ConnReq:
+		resp := p.GetResponse().(*cmpp.ConnResp)
+		resp.Status = uint32(smserror.ErrnoConnOthers)
+		return resp
+	case *smgp.LoginReq:
+		resp := p.GetResponse().(*smgp.LoginResp)
+		resp.Status = 20
+		return resp
+	case *sgip.BindReq:
+		resp := p.GetResponse().(*sgip.BindResp)
+		resp.Status = 1
+		return resp
+	case *smpp.BindRequest:
+		resp := p.GetResponse().(*smpp.BindResp)
+		resp.CommandStatus = smpp.ESME_RBINDFAIL
+		return resp
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package zysms
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+func TestListenerACLDeny(t *testing.T) {
+	tests := []struct {
+		proto codec.SmsProto
+		code  int
+	}{
+		{codec.CMPP30, int(smserror.ErrnoConnOthers)},
+		{codec.SMGP30, 20},
+		{codec.SGIP, 1},
+		{codec.SMPP34, int(smpp.ESME_RBINDFAIL)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.proto.String(), func(t *testing.T) {
+			s := New(tt.proto)
+			s.Authenticator = testAuth
+			acl, err := NewACL(nil, []string{"127.0.0.0/8"})
+			require.NoError(t, err)
+			s.ACL = acl
+			l, err := s.Listen("127.0.0.1:0")
+			require.NoError(t, err)
+			t.Cleanup(func() { shutdownNow(s) })
+
+			// 回复登录失败而不是直接断开
+			_, err = New(tt.proto).Dial(l.Addr().String(), testUid, testPwd, time.Second, nil)
+			var e *smserror.SmsError
+			require.ErrorAs(t, err, &e)
+			require.Equal(t, tt.code, e.Code)
+			require.Zero(t, l.Conns().Len())
+		})
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	listen := func(trusted *ACL) *Listener {
+		s := New(codec.CMPP30)
+		s.ProxyProtocol = true
+		s.TrustedProxies = trusted
+		l, err := s.Listen("127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { shutdownNow(s) })
+		return l
+	}
+	dial := func(l *Listener) net.Conn {
+		c, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		_, err = c.Write([]byte("PROXY TCP4 10.1.1.1 127.0.0.1 5000 7890\r\n"))
+		require.NoError(t, err)
+		return c
+	}
+
+	// 未设置可信代理时不接受 PROXY 头
+	c := dial(listen(nil))
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := c.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+
+	trusted, err := NewACL([]string{"127.0.0.1"}, nil)
+	require.NoError(t, err)
+	l := listen(trusted)
+	dial(l)
+	require.Eventually(t, func() bool { return len(l.Conns().IP("10.1.1.1")) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestProxyProtocolTls(t *testing.T) {
+	s := New(codec.CMPP30)
+	s.ProxyProtocol = true
+	_, err := s.ListenTlsConfig("127.0.0.1:0", &tls.Config{})
+	require.ErrorIs(t, err, errProxyTls)
+}
@@ -0,0 +1,49 @@
+package zysms
+
+// Account 服务端登录账号
+type Account struct {
+	ID       string
+	Password string
+	// ACL 不为空时只允许匹配的地址登录, 拒绝时 cmpp 回复 5, smgp 20 IP地址错, sgip 1, smpp ESME_RBINDFAIL
+	ACL *ACL
+}
+
+// Authenticator 服务端登录校验, id 为 SrcAddr/ClientID/LoginName/SystemID, 账号不存在时返回 nil
+type Authenticator interface {
+	Lookup(conn Conn, id string) (*Account, error)
+}
+
+// AuthenticatorFunc 函数形式的 Authenticator
+type AuthenticatorFunc func(conn Conn, id string) (*Account, error)
+
+func (f AuthenticatorFunc) Lookup(conn Conn, id string) (*Account, error) {
+	return f(conn, id)
+}
+
+// auth 连接使用的 Authenticator, 监听设置的优先
+func (c *sms_conn) auth() Authenticator {
+	if c.authenticator != nil {
+		return c.authenticator
+	}
+	return c.parent.Authenticator
+}
+
+// handleLogin 设置了 Authenticator 时由库校验登录并回复, 返回 true 表示报文已处理, 不再交给 OnRecv
+func (c *sms_conn) handleLogin(pdu PDU) (bool, error) {
+	auth := c.auth()
+	if auth == nil {
+		return false, nil
+	}
+	resp, id, err := c.action.verify(pdu, auth)
+	if resp == nil {
+		return false, nil
+	}
+	// 登录状态在 onSent 中设置, 账号需先于状态可见
+	if err == nil {
+		c.account = id
+	}
+	if e := c.SendPDU(resp); e != nil {
+		return true, e
+	}
+	return true, err
+}
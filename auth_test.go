@@ -0,0 +1,161 @@
+package zysms
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
+	"github.com/zhiyin2021/zysms/utils"
+	"go.uber.org/zap"
+)
+
+// loginStatus 取响应中的登录状态
+func loginStatus(t *testing.T, p codec.PDU) int {
+	t.Helper()
+	switch r := p.(type) {
+	case *smgp.LoginResp:
+		return int(r.Status)
+	case *sgip.BindResp:
+		return int(r.Status)
+	case *smpp.BindResp:
+		return int(r.CommandStatus)
+	}
+	t.Fatalf("unexpected %T", p)
+	return 0
+}
+
+func TestLoginVerify(t *testing.T) {
+	tests := []struct {
+		proto codec.SmsProto
+		// 密码错, 账号不存在, Lookup 出错, 重复登录
+		badPwd, unknown, lookupErr, repeat int
+		login                              func() codec.PDU
+		parse                              func(r io.Reader) (codec.PDU, error)
+	}{
+		{
+			proto: codec.SMGP30, badPwd: 21, unknown: 21, lookupErr: 1, repeat: 21,
+			login: func() codec.PDU {
+				p := smgp.NewLoginReq(smgp.V30).(*smgp.LoginReq)
+				p.ClientID = testUid
+				p.Secret = testPwd
+				p.Version = smgp.V30
+				return p
+			},
+			parse: func(r io.Reader) (codec.PDU, error) { return smgp.Parse(r, smgp.V30, zap.NewNop().Sugar()) },
+		},
+		{
+			proto: codec.SGIP, badPwd: 1, unknown: 1, lookupErr: 11, repeat: 2,
+			login: func() codec.PDU {
+				p := sgip.NewBindReq(sgip.V12, 0).(*sgip.BindReq)
+				p.LoginType = 1
+				p.LoginName = testUid
+				p.LoginPassword = testPwd
+				return p
+			},
+			parse: func(r io.Reader) (codec.PDU, error) { return sgip.Parse(r, sgip.V12, 0) },
+		},
+		{
+			proto:  codec.SMPP34,
+			badPwd: int(smpp.ESME_RINVPASWD), unknown: int(smpp.ESME_RINVSYSID),
+			lookupErr: int(smpp.ESME_RBINDFAIL), repeat: int(smpp.ESME_RALYBND),
+			login: func() codec.PDU {
+				p := smpp.NewBindRequest(smpp.Transceiver)
+				p.SystemID = testUid
+				p.Password = testPwd
+				return p
+			},
+			parse: func(r io.Reader) (codec.PDU, error) { return smpp.Parse(r, zap.NewNop().Sugar()) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.proto.String(), func(t *testing.T) {
+			_, l := testListen(t, tt.proto)
+			dial := func(uid, pwd string) error {
+				s := New(tt.proto)
+				t.Cleanup(func() { shutdownNow(s) })
+				_, err := s.Dial(l.Addr().String(), uid, pwd, time.Second, nil)
+				return err
+			}
+			requireCode := func(err error, code int) {
+				t.Helper()
+				var e *smserror.SmsError
+				require.ErrorAs(t, err, &e)
+				require.Equal(t, code, e.Code)
+			}
+
+			// 登录成功
+			_, c := testDial(t, tt.proto, l, nil)
+			require.True(t, c.IsAuth)
+			require.Equal(t, testUid, serverConn(t, l).account)
+
+			requireCode(dial(testUid, "bad"), tt.badPwd)
+			requireCode(dial("900002", testPwd), tt.unknown)
+
+			// 重复登录回复失败并断开
+			conn, err := net.Dial("tcp", l.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(time.Second))
+			for i, status := range []int{0, tt.repeat} {
+				writePDU(t, conn, tt.login())
+				resp, err := tt.parse(conn)
+				require.NoError(t, err, i)
+				require.Equal(t, status, loginStatus(t, resp), i)
+			}
+			_, err = tt.parse(conn)
+			require.Error(t, err)
+
+			// Lookup 出错
+			s := New(tt.proto)
+			s.Authenticator = AuthenticatorFunc(func(conn Conn, id string) (*Account, error) {
+				return nil, errors.New("db down")
+			})
+			l1, err := s.Listen("127.0.0.1:0")
+			require.NoError(t, err)
+			t.Cleanup(func() { shutdownNow(s) })
+			l = l1
+			requireCode(dial(testUid, testPwd), tt.lookupErr)
+		})
+	}
+}
+
+func TestSmgpAuthenticatorServer(t *testing.T) {
+	_, l := testListen(t, codec.SMGP30)
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	req := smgp.NewLoginReq(smgp.V30).(*smgp.LoginReq)
+	req.ClientID = testUid
+	req.Secret = testPwd
+	req.Version = smgp.V30
+	writePDU(t, conn, req)
+	p, err := smgp.Parse(conn, smgp.V30, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	resp := p.(*smgp.LoginResp)
+	require.Equal(t, smgp.Status(0), resp.Status)
+
+	// AuthenticatorServer = MD5(Status + AuthenticatorClient + secret)
+	authClient := md5.Sum(bytes.Join([][]byte{[]byte(testUid),
+		make([]byte, 7),
+		[]byte(testPwd),
+		[]byte(utils.Timestamp2Str(req.Timestamp))},
+		nil))
+	authServer := md5.Sum(bytes.Join([][]byte{binary.BigEndian.AppendUint32(nil, 0),
+		authClient[:],
+		[]byte(testPwd)},
+		nil))
+	require.Equal(t, string(bytes.TrimRight(authServer[:], "\x00")), resp.AuthenticatorServer)
+}
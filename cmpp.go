@@ -1,7 +1,11 @@
 package zysms
 
 import (
+	"bytes"
+	"crypto/md5"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -9,6 +13,7 @@ import (
 	"github.com/zhiyin2021/zysms/codec"
 	"github.com/zhiyin2021/zysms/enum"
 	"github.com/zhiyin2021/zysms/smserror"
+	"github.com/zhiyin2021/zysms/utils"
 )
 
 type cmpp_action struct {
@@ -49,19 +54,22 @@ func (c *cmpp_action) login(uid string, pwd string) error {
 	c.IsAuth = true
 	return nil
 }
-func (c *cmpp_action) logout() {
-	c.SendPDU(cmpp.NewCancelReq(c.Typ))
+
+// logout cmpp 使用 CMPP_TERMINATE 退出, CMPP_CANCEL 为删除短信
+func (c *cmpp_action) logout() codec.PDU {
+	return cmpp.NewTerminateReq(c.Typ)
 }
 
 // RecvAndUnpackPkt receives cmpp byte stream, and unpack it to some cmpp packet structure.
 func (c *cmpp_action) recv() (codec.PDU, error) {
-	if c.Connected == enum.CONN_DISCONNECTED {
+	if atomic.LoadInt32(&c.Connected) == enum.CONN_DISCONNECTED {
 		return nil, smserror.ErrConnIsClosed
 	}
 	pdu, err := cmpp.Parse(c.Conn, c.Typ, c.logger)
 	if err != nil {
 		return nil, err
 	}
+	c.releaseWindow(pdu)
 
 	switch p := pdu.(type) {
 	case *cmpp.ActiveTestReq: // 当收到心跳请求,内部直接回复心跳,并递归继续获取数据
@@ -77,7 +85,8 @@ func (c *cmpp_action) recv() (codec.PDU, error) {
 		if c.checkVer && p.Version != c.Typ {
 			return nil, fmt.Errorf("cmpp version not match [ local: %d != remote: %d ]", c.Typ, p.Version)
 		}
-	case *cmpp.CancelReq:
+	case *cmpp.TerminateReq: // 当收到退出请求,内部直接回复退出
+		c.setBindState(enum.BIND_UNBINDING)
 		resp := p.GetResponse()
 		c.SendPDU(resp)
 		time.Sleep(100 * time.Millisecond)
@@ -98,6 +107,108 @@ func (c *cmpp_action) recv() (codec.PDU, error) {
 
 }
 
+// verify AuthSrc = MD5(SrcAddr + 9字节0 + secret + timestamp)
+func (c *cmpp_action) verify(pdu codec.PDU, auth Authenticator) (codec.PDU, string, error) {
+	req, ok := pdu.(*cmpp.ConnReq)
+	if !ok {
+		return nil, "", nil
+	}
+	resp := req.GetResponse().(*cmpp.ConnResp)
+	fail := func(status uint8) (codec.PDU, string, error) {
+		resp.Status = uint32(status)
+		return resp, "", smserror.ConnRspStatusErrMap[status]
+	}
+	if c.IsAuth {
+		return fail(smserror.ErrnoConnOthers)
+	}
+	acc, err := auth.Lookup(c, req.SrcAddr)
+	if err != nil {
+		resp.Status = uint32(smserror.ErrnoConnOthers)
+		return resp, "", err
+	}
+	if acc == nil {
+		return fail(smserror.ErrnoConnInvalidSrcAddr)
+	}
+	authSrc := md5.Sum(bytes.Join([][]byte{[]byte(req.SrcAddr),
+		make([]byte, 9),
+		[]byte(acc.Password),
+		[]byte(utils.Timestamp2Str(req.Timestamp))},
+		nil))
+	// ReadStr 会去掉末尾的0
+	if req.AuthSrc != strings.TrimRight(string(authSrc[:]), "\x00") {
+		return fail(smserror.ErrnoConnAuthFailed)
+	}
+	resp.AuthSrc = string(authSrc[:])
+	resp.Secret = acc.Password
+	if !c.certAllowed(req.SrcAddr) {
+		return fail(smserror.ErrnoConnAuthFailed)
+	}
+	if !c.allowed(acc) || !c.register(req.SrcAddr) {
+		return fail(smserror.ErrnoConnOthers)
+	}
+	return resp, req.SrcAddr, nil
+}
+
+func (c *cmpp_action) submit(msg *Message) ([]codec.PDU, error) {
+	parts, err := splitText(msg)
+	if err != nil {
+		return nil, err
+	}
+	pdus := make([]codec.PDU, 0, len(parts))
+	for i, sm := range parts {
+		p := cmpp.NewSubmitReq(c.Typ).(*cmpp.SubmitReq)
+		p.PkTotal = uint8(len(parts))
+		p.PkNumber = uint8(i + 1)
+		p.RegisteredDelivery = 0
+		if msg.RequestReport {
+			p.RegisteredDelivery = 1
+		}
+		p.ServiceId = utils.MapItem(c.extParam, "service_id", "")
+		p.FeeUserType = 0
+		p.FeeTerminalId = ""
+		p.FeeType = "01"
+		p.FeeCode = "0"
+		p.ValidTime = ""
+		p.MsgSrc = c.account
+		p.SrcId = msg.From
+		p.DestTerminalId = msg.To
+		p.MsgFmt = sm.DataCoding()
+		if len(parts) > 1 {
+			p.TpUdhi = 1
+		}
+		p.Message = *sm
+		pdus = append(pdus, p)
+	}
+	return pdus, nil
+}
+
+func (c *cmpp_action) submitResult(resp codec.PDU) (string, error) {
+	p, ok := resp.(*cmpp.SubmitResp)
+	if !ok {
+		return "", smserror.ErrRespNotMatch
+	}
+	if p.Result != 0 {
+		return "", smserror.NewSmsErr(int(p.Result), "cmpp.submit.error")
+	}
+	return strconv.FormatUint(p.MsgId, 10), nil
+}
+
+func (c *cmpp_action) report(pdu codec.PDU) (*Report, bool) {
+	p, ok := pdu.(*cmpp.DeliverReq)
+	if !ok || p.Report == nil {
+		return nil, false
+	}
+	r := p.Report
+	return &Report{
+		MsgID:      strconv.FormatUint(r.MsgId, 10),
+		Dest:       r.DestTerminalId,
+		Stat:       r.Stat,
+		SubmitTime: r.SubmitTime,
+		DoneTime:   r.DoneTime,
+		PDU:        p,
+	}, true
+}
+
 func (c *cmpp_action) active_test() error {
 	p := cmpp.NewActiveTestReq(c.Typ)
 	c.activeTestReq(p.GetSequenceNumber())
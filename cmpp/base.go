@@ -12,6 +12,7 @@ import (
 type base struct {
 	Header
 	OptionalParameters codec.OptionalFields
+	tlvOrder           codec.TlvOrder
 	Version            codec.Version
 }
 
@@ -77,6 +78,7 @@ func (c *base) unmarshalOptionalParam(optParam []byte) (err error) {
 		var field codec.Field
 		if err = field.Unmarshal(reader); err == nil {
 			c.OptionalParameters[field.Tag] = field
+			c.tlvOrder.Add(field.Tag)
 		} else {
 			return
 		}
@@ -92,10 +94,8 @@ func (c *base) marshal(b *codec.BytesWriter, bodyWriter func(*codec.BytesWriter)
 		bodyWriter(bodyBuf)
 	}
 
-	// optional body
-	for _, v := range c.OptionalParameters {
-		v.Marshal(bodyBuf)
-	}
+	// optional body, 按接收或注册的顺序
+	c.tlvOrder.Marshal(bodyBuf, c.OptionalParameters)
 
 	// write header
 	c.CommandLength = uint32(PDU_HEADER_SIZE + bodyBuf.Len())
@@ -108,6 +108,7 @@ func (c *base) marshal(b *codec.BytesWriter, bodyWriter func(*codec.BytesWriter)
 // RegisterOptionalParam register optional param.
 func (c *base) RegisterOptionalParam(tlv codec.Field) {
 	c.OptionalParameters[tlv.Tag] = tlv
+	c.tlvOrder.Add(tlv.Tag)
 }
 
 // IsOk is status ok.
@@ -12,7 +12,7 @@ type CancelReq struct {
 }
 type CancelResp struct {
 	base
-	SuccId uint32
+	SuccessId uint32 // 4字节 0:成功 1:失败
 }
 
 func NewCancelReq(ver codec.Version) codec.PDU {
@@ -47,13 +47,13 @@ func (b *CancelReq) GetResponse() codec.PDU {
 
 func (p *CancelResp) Marshal(w *codec.BytesWriter) {
 	p.base.marshal(w, func(bw *codec.BytesWriter) {
-		bw.WriteU32(p.SuccId)
+		bw.WriteU32(p.SuccessId)
 	})
 }
 
 func (p *CancelResp) Unmarshal(w *codec.BytesReader) error {
 	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
-		p.SuccId = br.ReadU32()
+		p.SuccessId = br.ReadU32()
 		return br.Err()
 	})
 }
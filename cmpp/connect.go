@@ -53,12 +53,15 @@ func (p *ConnReq) Marshal(w *codec.BytesWriter) {
 	} else {
 		ts = utils.Timestamp2Str(p.Timestamp)
 	}
-	md5 := md5.Sum(bytes.Join([][]byte{[]byte(p.SrcAddr),
-		make([]byte, 9),
-		[]byte(p.Secret),
-		[]byte(ts)},
-		nil))
-	p.AuthSrc = string(md5[:])
+	// 已有认证码且无密钥时(如解析所得)保持原值, 保证重新编码字节一致
+	if p.Secret != "" || p.AuthSrc == "" {
+		md5 := md5.Sum(bytes.Join([][]byte{[]byte(p.SrcAddr),
+			make([]byte, 9),
+			[]byte(p.Secret),
+			[]byte(ts)},
+			nil))
+		p.AuthSrc = string(md5[:])
+	}
 
 	p.base.marshal(w, func(bw *codec.BytesWriter) {
 		bw.WriteStr(p.SrcAddr, 6)
@@ -90,22 +93,20 @@ func (p *ConnResp) Marshal(w *codec.BytesWriter) {
 	p.base.marshal(w, func(bw *codec.BytesWriter) {
 		bs := make([]byte, 4)
 		binary.BigEndian.PutUint32(bs, p.Status)
-		var hash [16]byte
 		if p.Version == V30 {
 			bw.WriteU32(p.Status)
-			hash = md5.Sum(bytes.Join([][]byte{bs,
-				[]byte(p.AuthSrc),
-				[]byte(p.Secret)},
-				nil))
 		} else {
-			bw.WriteByte(bs[3])
-			hash = md5.Sum(bytes.Join([][]byte{
-				{bs[3]},
+			bs = bs[3:]
+			bw.WriteByte(bs[0])
+		}
+		// AuthSrc 为空时(如解析所得)保持原认证码, 保证重新编码字节一致
+		if p.AuthSrc != "" {
+			hash := md5.Sum(bytes.Join([][]byte{bs,
 				[]byte(p.AuthSrc),
 				[]byte(p.Secret)},
 				nil))
+			p.AuthIsmg = string(hash[:])
 		}
-		p.AuthIsmg = string(hash[:])
 		bw.WriteStr(p.AuthIsmg, 16)
 		bw.WriteByte(byte(p.Version))
 	})
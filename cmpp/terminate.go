@@ -37,7 +37,7 @@ func (p *TerminateReq) Unmarshal(w *codec.BytesReader) error {
 }
 func (p *TerminateReq) GetResponse() codec.PDU {
 	return &TerminateResp{
-		base: newBase(p.Version, CMPP_TERMINATE_RESP, 0),
+		base: newBase(p.Version, CMPP_TERMINATE_RESP, p.SequenceNumber),
 	}
 }
 
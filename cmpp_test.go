@@ -0,0 +1,40 @@
+package zysms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+func TestCmppCancel(t *testing.T) {
+	s, l := testListen(t, codec.CMPP30)
+	s.OnCancel = func(c Conn, msgId string) bool {
+		return msgId == "123"
+	}
+	s.OnRecv = func(c Conn, p PDU) {
+		if _, ok := p.(*cmpp.CancelReq); ok {
+			t.Error("cancel handled by OnCancel passed to OnRecv")
+		}
+	}
+	_, c := testDial(t, codec.CMPP30, l, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, c.Cancel(ctx, "123"))
+	require.ErrorIs(t, c.Cancel(ctx, "456"), smserror.ErrCancelFailed)
+	require.ErrorIs(t, c.Cancel(ctx, "abc"), smserror.ErrMethodParamsInvalid)
+}
+
+func TestCancelUnsupported(t *testing.T) {
+	_, l := testListen(t, codec.SMGP30)
+	_, c := testDial(t, codec.SMGP30, l, nil)
+
+	err := c.Cancel(context.Background(), "123")
+	require.ErrorIs(t, err, smserror.ErrProtoNotSupport)
+	require.Contains(t, err.Error(), "cmpp")
+}
@@ -13,6 +13,9 @@ const (
 	SGIP
 	SMPP33
 	SMPP34
+	SMPP50
+	// AUTO 监听时按登录报文识别协议, 仅用于 Listen
+	AUTO
 )
 
 var protoMap = map[SmsProto]Version{
@@ -25,6 +28,7 @@ var protoMap = map[SmsProto]Version{
 	SGIP:   0x12,
 	SMPP33: 0x33,
 	SMPP34: 0x34,
+	SMPP50: 0x50,
 }
 var protoVer = map[SmsProto]string{
 	CMPP20: "cmpp20",
@@ -36,6 +40,8 @@ var protoVer = map[SmsProto]string{
 	SGIP:   "sgip",
 	SMPP33: "smpp33",
 	SMPP34: "smpp34",
+	SMPP50: "smpp50",
+	AUTO:   "auto",
 }
 
 var proto = map[SmsProto]string{
@@ -48,6 +54,8 @@ var proto = map[SmsProto]string{
 	SGIP:   "sgip",
 	SMPP33: "smpp",
 	SMPP34: "smpp",
+	SMPP50: "smpp",
+	AUTO:   "auto",
 }
 
 func (s SmsProto) Version() Version {
@@ -5,6 +5,8 @@ type Version byte
 type CommandStatus uint32
 
 type Header interface {
+	// GetCommandID returns command id.
+	GetCommandID() uint32
 	AssignSequenceNumber()
 	// ResetSequenceNumber resets sequence number.
 	ResetSequenceNumber()
@@ -0,0 +1,182 @@
+package codec_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+)
+
+type protoCase struct {
+	name   string
+	create func(codec.CommandId) (codec.PDU, error)
+	parse  func([]byte) (codec.PDU, error)
+}
+
+var protoCases = []protoCase{
+	{
+		name: "cmpp20",
+		create: func(id codec.CommandId) (codec.PDU, error) {
+			return cmpp.CreatePDUHeader(cmpp.Header{CommandID: id}, cmpp.V20)
+		},
+		parse: func(b []byte) (codec.PDU, error) { return cmpp.Parse(bytes.NewReader(b), cmpp.V20, nil) },
+	},
+	{
+		name: "cmpp30",
+		create: func(id codec.CommandId) (codec.PDU, error) {
+			return cmpp.CreatePDUHeader(cmpp.Header{CommandID: id}, cmpp.V30)
+		},
+		parse: func(b []byte) (codec.PDU, error) { return cmpp.Parse(bytes.NewReader(b), cmpp.V30, nil) },
+	},
+	{
+		name: "smgp30",
+		create: func(id codec.CommandId) (codec.PDU, error) {
+			return smgp.CreatePDUHeader(smgp.Header{CommandID: id}, smgp.V30)
+		},
+		parse: func(b []byte) (codec.PDU, error) { return smgp.Parse(bytes.NewReader(b), smgp.V30, nil) },
+	},
+	{
+		name: "sgip",
+		create: func(id codec.CommandId) (codec.PDU, error) {
+			return sgip.CreatePDUHeader(sgip.Header{CommandID: id}, sgip.V12)
+		},
+		parse: func(b []byte) (codec.PDU, error) { return sgip.Parse(bytes.NewReader(b), sgip.V12, 0) },
+	},
+	{
+		name:   "smpp",
+		create: smpp.CreatePDUFromCmdID,
+		parse:  func(b []byte) (codec.PDU, error) { return smpp.Parse(bytes.NewReader(b), nil) },
+	},
+}
+
+// commandIds 可能的命令字, 不支持的由 create 过滤
+func commandIds() (ids []codec.CommandId) {
+	for _, r := range [][2]uint32{{0, 0x30}, {0x100, 0x120}, {0x1000, 0x1001}} {
+		for id := r[0]; id < r[1]; id++ {
+			ids = append(ids, codec.CommandId(id), codec.CommandId(0x80000000|id))
+		}
+	}
+	return
+}
+
+// fill 随机填充字符串字段及消息内容
+func fill(r *rand.Rand, pdu codec.PDU) {
+	v := reflect.ValueOf(pdu).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f, sf := v.Field(i), v.Type().Field(i)
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		switch m := f.Addr().Interface().(type) {
+		case *string:
+			*m = randStr(r, 1+r.Intn(12))
+		case *codec.ShortMessage:
+			m.SetMessage(randStr(r, r.Intn(60)), codec.ASCII)
+		case *smpp.ShortMessage:
+			m.SetMessageWithEncoding(randStr(r, r.Intn(60)), codec.ASCII)
+		}
+	}
+}
+
+func randStr(r *rand.Rand, n int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// randTlvs 厂商自定义范围内的 tag, 长度可以为 0, 顺序随机
+func randTlvs(r *rand.Rand) (fields []codec.Field) {
+	for _, i := range r.Perm(r.Intn(5)) {
+		data := make([]byte, r.Intn(8))
+		r.Read(data)
+		fields = append(fields, codec.NewTlv(codec.Tag(0x1480+i), data))
+	}
+	return
+}
+
+// tlvTags 报文末尾 TLV 的 tag 顺序
+func tlvTags(data []byte, n int) (tags []codec.Tag) {
+	data = data[len(data)-n:]
+	for len(data) >= 4 {
+		tags = append(tags, codec.Tag(binary.BigEndian.Uint16(data)))
+		data = data[4+binary.BigEndian.Uint16(data[2:]):]
+	}
+	return
+}
+
+func TestRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, pc := range protoCases {
+		total := 0
+		for _, id := range commandIds() {
+			if _, err := pc.create(id); err != nil {
+				continue
+			}
+			total++
+			for i := 0; i < 50; i++ {
+				pdu, _ := pc.create(id)
+				fill(r, pdu)
+				tlvs := randTlvs(r)
+				size := 0
+				for _, f := range tlvs {
+					pdu.RegisterOptionalParam(f)
+					size += 4 + len(f.Data)
+				}
+				name := fmt.Sprintf("%s %T #%d", pc.name, pdu, i)
+
+				w := codec.NewWriter()
+				pdu.Marshal(w)
+				data := w.Bytes()
+
+				p1, err := pc.parse(data)
+				require.NoError(t, err, name)
+				require.Equal(t, reflect.TypeOf(pdu), reflect.TypeOf(p1), name)
+				w1 := codec.NewWriter()
+				p1.Marshal(w1)
+				require.Equal(t, data, w1.Bytes(), name)
+
+				// TLV 按注册顺序写入
+				var tags []codec.Tag
+				for _, f := range tlvs {
+					tags = append(tags, f.Tag)
+				}
+				require.Equal(t, tags, tlvTags(data, size), name)
+			}
+		}
+		require.NotZero(t, total, pc.name)
+		t.Logf("%s: %d pdu types", pc.name, total)
+	}
+}
+
+func TestZeroLengthTlv(t *testing.T) {
+	pdu := smpp.NewSubmitSM().(*smpp.SubmitSM)
+	pdu.RegisterOptionalParam(codec.NewTlv(codec.TagAlertOnMessageDelivery, []byte{}))
+	pdu.RegisterOptionalParam(codec.NewTlv(codec.TagUserMessageReference, nil))
+	w := codec.NewWriter()
+	pdu.Marshal(w)
+	data := w.Bytes()
+	// Data 为 nil 的参数不写入, 长度为 0 的参数写入
+	require.Equal(t, []codec.Tag{codec.TagAlertOnMessageDelivery}, tlvTags(data, 4))
+	require.Equal(t, []byte{0x13, 0x0c, 0, 0}, data[len(data)-4:])
+
+	p1, err := smpp.Parse(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+	f, ok := p1.(*smpp.SubmitSM).OptionalParameters[codec.TagAlertOnMessageDelivery]
+	require.True(t, ok)
+	require.NotNil(t, f.Data)
+	w1 := codec.NewWriter()
+	p1.Marshal(w1)
+	require.Equal(t, data, w1.Bytes())
+}
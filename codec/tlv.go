@@ -5,6 +5,9 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"slices"
+
+	"github.com/zhiyin2021/zysms/smserror"
 )
 
 // Tag is the tag of a Tag-Length-Value (TLV) field.
@@ -49,6 +52,7 @@ const (
 	TagLanguageIndicator        Tag = 0x020D
 	TagSarTotalSegments         Tag = 0x020E
 	TagSarSegmentSeqnum         Tag = 0x020F
+	TagScInterfaceVersion       Tag = 0x0210
 	TagCallbackNumPresInd       Tag = 0x0302
 	TagCallbackNumAtag          Tag = 0x0303
 	TagNumberOfMessages         Tag = 0x0304
@@ -61,13 +65,34 @@ const (
 	TagDeliveryFailureReason    Tag = 0x0425
 	TagMoreMessagesToSend       Tag = 0x0426
 	TagMessageStateOption       Tag = 0x0427
+	TagCongestionState          Tag = 0x0428
 	TagUssdServiceOp            Tag = 0x0501
-	TagDisplayTime              Tag = 0x1201
-	TagSmsSignal                Tag = 0x1203
-	TagMsValidity               Tag = 0x1204
-	TagAlertOnMessageDelivery   Tag = 0x130C
-	TagItsReplyType             Tag = 0x1380
-	TagItsSessionInfo           Tag = 0x1383
+	// smpp 5.0
+	TagBroadcastChannelIndicator  Tag = 0x0600
+	TagBroadcastContentType       Tag = 0x0601
+	TagBroadcastContentTypeInfo   Tag = 0x0602
+	TagBroadcastMessageClass      Tag = 0x0603
+	TagBroadcastRepNum            Tag = 0x0604
+	TagBroadcastFrequencyInterval Tag = 0x0605
+	TagBroadcastAreaIdentifier    Tag = 0x0606
+	TagBroadcastErrorStatus       Tag = 0x0607
+	TagBroadcastAreaSuccess       Tag = 0x0608
+	TagBroadcastEndTime           Tag = 0x0609
+	TagBroadcastServiceGroup      Tag = 0x060A
+	TagBillingIdentification      Tag = 0x060B
+	TagSourceNetworkID            Tag = 0x060D
+	TagDestNetworkID              Tag = 0x060E
+	TagSourceNodeID               Tag = 0x060F
+	TagDestNodeID                 Tag = 0x0610
+	TagDestAddrNpResolution       Tag = 0x0611
+	TagDestAddrNpInformation      Tag = 0x0612
+	TagDestAddrNpCountry          Tag = 0x0613
+	TagDisplayTime                Tag = 0x1201
+	TagSmsSignal                  Tag = 0x1203
+	TagMsValidity                 Tag = 0x1204
+	TagAlertOnMessageDelivery     Tag = 0x130C
+	TagItsReplyType               Tag = 0x1380
+	TagItsSessionInfo             Tag = 0x1383
 	/*
 		消息类型
 		0TP (for a one-time password)
@@ -100,6 +125,66 @@ func NewTlv(tag Tag, data []byte) Field {
 	return Field{Tag: tag, Data: data}
 }
 
+// NewTlvU8 1 字节整数
+func NewTlvU8(tag Tag, v uint8) Field {
+	return Field{Tag: tag, Data: []byte{v}}
+}
+
+// NewTlvU16 2 字节整数
+func NewTlvU16(tag Tag, v uint16) Field {
+	return Field{Tag: tag, Data: binary.BigEndian.AppendUint16(nil, v)}
+}
+
+// NewTlvU32 4 字节整数
+func NewTlvU32(tag Tag, v uint32) Field {
+	return Field{Tag: tag, Data: binary.BigEndian.AppendUint32(nil, v)}
+}
+
+// NewTlvCStr 以 0 结尾的字符串
+func NewTlvCStr(tag Tag, v string) Field {
+	return Field{Tag: tag, Data: append([]byte(v), 0)}
+}
+
+// TlvType TLV 值类型
+type TlvType byte
+
+const (
+	TlvOctet TlvType = iota
+	TlvUint8
+	TlvUint16
+	TlvUint32
+	TlvCStr
+)
+
+func (t TlvType) String() string {
+	switch t {
+	case TlvUint8:
+		return "uint8"
+	case TlvUint16:
+		return "uint16"
+	case TlvUint32:
+		return "uint32"
+	case TlvCStr:
+		return "c-string"
+	}
+	return "octet"
+}
+
+// TlvSpec TLV 定义, Min/Max 为值的字节数
+type TlvSpec struct {
+	Name     string
+	Type     TlvType
+	Min, Max int
+}
+
+// Check 校验值的长度
+func (s TlvSpec) Check(f Field) error {
+	if n := len(f.Data); n < s.Min || n > s.Max {
+		return fmt.Errorf("%w: %s(0x%s) length %d out of range [%d, %d]", smserror.ErrInvalidTlv, s.Name, f.Tag.Hex(), n, s.Min, s.Max)
+	}
+	return nil
+}
+
 // String implements the Data interface.
 func (t *Field) String() string {
 	if l := len(t.Data); l > 0 && t.Data[l-1] == 0x00 {
@@ -124,14 +209,15 @@ func (t *Field) UInt64() uint64 {
 	}
 }
 
-// Marshal to writer.
+// Marshal to writer, Data 为 nil 时不写入; 长度为 0 的参数(如 alert_on_message_delivery)需设置为 []byte{}.
 func (t *Field) Marshal(w *BytesWriter) {
-	if len(t.Data) > 0 {
-		w.Grow(4 + len(t.Data))
-		w.WriteU16(uint16(t.Tag))
-		w.WriteU16(uint16(len(t.Data)))
-		_, _ = w.Write(t.Data)
+	if t.Data == nil {
+		return
 	}
+	w.Grow(4 + len(t.Data))
+	w.WriteU16(uint16(t.Tag))
+	w.WriteU16(uint16(len(t.Data)))
+	_, _ = w.Write(t.Data)
 }
 
 // Unmarshal from reader.
@@ -139,5 +225,35 @@ func (t *Field) Unmarshal(b *BytesReader) (err error) {
 	t.Tag = Tag(b.ReadU16())
 	ln := b.ReadU16()
 	t.Data = b.ReadN(int(ln))
+	if t.Data == nil {
+		// 收到的长度为 0 的参数, 重新序列化时保留
+		t.Data = []byte{}
+	}
 	return b.Err()
 }
+
+// TlvOrder 记录 TLV 接收或注册的顺序, 解析后重新序列化与原报文一致
+type TlvOrder []Tag
+
+// Add 记录新的 tag, 已存在的保持原位置
+func (o *TlvOrder) Add(tag Tag) {
+	if !slices.Contains(*o, tag) {
+		*o = append(*o, tag)
+	}
+}
+
+// Marshal 按记录的顺序写入, 直接写入 map 未经记录的参数按 tag 排序追加在后
+func (o TlvOrder) Marshal(w *BytesWriter, fields OptionalFields) {
+	var rest []Tag
+	for tag := range fields {
+		if !slices.Contains(o, tag) {
+			rest = append(rest, tag)
+		}
+	}
+	slices.Sort(rest)
+	for _, tag := range slices.Concat(o, rest) {
+		if f, ok := fields[tag]; ok {
+			f.Marshal(w)
+		}
+	}
+}
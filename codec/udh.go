@@ -169,6 +169,20 @@ func (u UDH) GetConcatInfo() (totalParts, partNum, mref byte, found bool) {
 	return
 }
 
+// GetConcatRef return the FIRST concatenated message IE,
+// supports both 8-bit and 16-bit reference number
+func (u UDH) GetConcatRef() (totalParts, partNum byte, mref uint16, found bool) {
+	for i := range u {
+		switch d := u[i].Data; {
+		case u[i].ID == UDH_CONCAT_MSG_8_BIT_REF && len(d) == 3:
+			return d[1], d[2], uint16(d[0]), true
+		case u[i].ID == UDH_CONCAT_MSG_16_BIT_REF && len(d) == 4:
+			return d[2], d[3], uint16(d[0])<<8 | uint16(d[1]), true
+		}
+	}
+	return
+}
+
 // InfoElement represent a 3 parts Information-Element
 // as defined in 3GPP TS 23.040 Section 9.2.3.24
 // Each InfoElement is comprised of it's identifier and data
@@ -186,6 +200,14 @@ func NewIEConcatMessage(totalParts, partNum, mref byte) InfoElement {
 	}
 }
 
+// NewIEConcatMessage16 turn a new IE element for concat message info with 16-bit reference number
+func NewIEConcatMessage16(totalParts, partNum byte, mref uint16) InfoElement {
+	return InfoElement{
+		ID:   UDH_CONCAT_MSG_16_BIT_REF,
+		Data: []byte{byte(mref >> 8), byte(mref), totalParts, partNum},
+	}
+}
+
 // UnmarshalBinary unmarshal IE from binary in src, only read a single IE,
 // expect src at least of length 2 with correct IE format:
 //
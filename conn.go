@@ -20,6 +20,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// logoutTimeout 退出时等待对端响应的时间
+const logoutTimeout = time.Second
+
 type activeTestItem struct {
 	time  time.Time
 	timer *time.Timer
@@ -46,36 +49,65 @@ type sms_conn struct {
 	checkVer       bool
 	autoActiveResp bool
 	systemType     string
+	// bindType smpp 登录方式 enum.BIND_TX/RX/TRX
+	bindType int32
 
 	action sms_action
 	delay  *utils.Queue
 
 	Connected int32
 	IsAuth    bool
+	bindState int32
 	cache     *cache.Memory
 
 	parent *SMS
 
 	pduWriter      *codec.BytesWriter
 	activeTestPool sync.Pool
+
+	pending *pendingTable
+	// sending 正在发送的请求数, 包括等待限速或窗口的 SendPDU, 见 drain
+	sending int32
+	// dispatching 接收协程正在处理报文或回调, 此时无法读取响应, 见 Close
+	dispatching    int32
+	requestTimeout time.Duration
+	window         *window
+	limiter        *utils.Limiter
+	account        string
+	authenticator  Authenticator
+	// outbind 收到 smpp outbind 后用于 bind_receiver 的账号
+	outbind *Account
+	// loginType sgip 登录类型, 客户端登录时发送, 服务端不为 0 时只接受该类型
+	loginType byte
+	// listener 服务端连接所属的监听
+	listener *Listener
 }
 
 type sms_action interface {
 	login(uid, pwd string) error
-	logout()
+	// logout 生成退出请求
+	logout() codec.PDU
 	recv() (codec.PDU, error)
 	active_test() error
+	// verify 校验登录请求并生成响应, pdu 不是登录请求时返回 nil
+	verify(pdu codec.PDU, auth Authenticator) (codec.PDU, string, error)
+	// submit 生成提交报文, 长短信每个分片一个报文
+	submit(msg *Message) ([]codec.PDU, error)
+	// submitResult 从提交响应中取出消息ID
+	submitResult(resp codec.PDU) (string, error)
+	// report 转换状态报告, 消息ID格式同 submitResult
+	report(pdu codec.PDU) (*Report, bool)
 }
 
-func newConn(conn net.Conn, parent *SMS) *sms_conn {
+func newConn(conn net.Conn, parent *SMS, proto codec.SmsProto) *sms_conn {
 	sid := utils.Md5(fmt.Sprintf("%s%s%d", conn.RemoteAddr(), conn.LocalAddr(), time.Now().UnixNano()))[8:24]
 	addr := fmt.Sprintf("%s->%s", conn.LocalAddr(), conn.RemoteAddr())
 	c := &sms_conn{
 		Conn:           conn,
 		sid:            sid,
-		Typ:            parent.proto.Version(),
-		Protocol:       parent.proto,
-		logger:         logger.With("sid", sid, "addr", addr, "v", parent.proto.String()),
+		Typ:            proto.Version(),
+		Protocol:       proto,
+		logger:         logger.With("sid", sid, "addr", addr, "v", proto.String()),
 		extParam:       parent.extParam,
 		checkVer:       false,
 		autoActiveResp: true,
@@ -86,15 +118,19 @@ func newConn(conn net.Conn, parent *SMS) *sms_conn {
 		parent:         parent,
 		pduWriter:      codec.NewWriter(),
 		activeTestPool: sync.Pool{New: func() any { return new(activeTestItem) }},
+		pending:        newPendingTable(),
+		requestTimeout: 30 * time.Second,
+		bindType:       enum.BIND_TRX,
+		nodeId:         parent.NodeId,
 	}
-	switch parent.proto {
+	switch proto {
 	case codec.CMPP20, codec.CMPP21, codec.CMPP30:
 		c.action = newCmpp(c)
 	case codec.SMGP30:
 		c.action = newSmgp(c)
 	case codec.SGIP:
 		c.action = newSgip(c)
-	case codec.SMPP33, codec.SMPP34:
+	case codec.SMPP33, codec.SMPP34, codec.SMPP50:
 		c.action = newSmpp(c)
 	default:
 		return nil
@@ -103,14 +139,29 @@ func newConn(conn net.Conn, parent *SMS) *sms_conn {
 	atomic.StoreInt32(&c.Connected, 1)
 	return c
 }
+
+// Proto 连接使用的协议, 自动识别时为识别结果
+func (c *sms_conn) Proto() codec.SmsProto {
+	return c.Protocol
+}
 func (c *sms_conn) IsConnected() bool {
-	return c.Connected == 1
+	return atomic.LoadInt32(&c.Connected) == 1
 }
 func (c *sms_conn) Auth(uid string, pwd string) error {
 	if c.action == nil {
 		return smserror.ErrProtoNotSupport
 	}
-	return c.action.login(uid, pwd)
+	if err := c.action.login(uid, pwd); err != nil {
+		return err
+	}
+	c.account = uid
+	c.setBindState(c.bindType)
+	return nil
+}
+
+// Account 登录账号
+func (c *sms_conn) Account() string {
+	return c.account
 }
 func (c *sms_conn) SetData(data any) {
 	c.Data = data
@@ -167,16 +218,30 @@ func (c *sms_conn) sendActiveTest() (int32, error) {
 	n := atomic.AddInt32(&c.counter, 1)
 	return n, nil
 }
+
+// Close 已登录时先发送退出请求, 等待响应后关闭连接.
+// 接收协程正在回调中(如在 OnRecv 中调用)时无法读取响应, 只发送退出请求不等待
 func (c *sms_conn) Close() {
+	if atomic.LoadInt32(&c.dispatching) == 1 {
+		if c.unbind() {
+			c.SendPDU(c.action.logout())
+		}
+		c.close()
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, logoutTimeout)
+	defer cancel()
+	c.shutdown(ctx, false)
+}
+
+// close 直接关闭连接, 不发送退出请求
+func (c *sms_conn) close() {
 	if atomic.CompareAndSwapInt32(&c.Connected, enum.CONN_CONNECTED, enum.CONN_DISCONNECTED) {
 		// c.logger.Warnln("connection closing.")
-		if c.IsAuth {
-			if c.action != nil {
-				c.action.logout()
-			}
-			time.Sleep(100 * time.Millisecond)
-			c.IsAuth = false
-		}
+		c.setBindState(enum.BIND_UNBINDING)
+		c.IsAuth = false
+		c.pending.failAll(smserror.ErrConnIsClosed)
+		c.setBindState(enum.BIND_CLOSED)
 		c.stop()
 		c.Conn.Close()
 		// c.logger.Warnln("connection closed.")
@@ -185,9 +250,21 @@ func (c *sms_conn) Close() {
 
 /*
 active_count 心跳失败次数
-active_interval 心跳间隔
+active_interval 心跳间隔(秒), 0 不发送心跳, 默认 5
 check_version 是否校验版本
 system_type 系统类型[smpp 特有]
+bind_type 登录方式 tx/rx/trx, 默认 trx[smpp 特有]
+login_type 登录类型 1:SP 连接 SMG 2:SMG 连接 SP, 默认 1[sgip 特有]
+service_id 业务代码, SendText 使用
+service_type 服务类型[smpp 特有], SendText 使用
+corp_id 企业代码[sgip 特有], SendText 使用
+node_id 节点编号[sgip 特有], 默认 SMS.NodeId, SP 为 3AAAAQQQQQ(AAAA 区号, QQQQQ 企业代码)
+request_timeout 请求等待响应超时(秒)
+window_size 滑动窗口大小,未收到响应的最大请求数,0不限制
+window_timeout 窗口位置超时释放时间(秒)
+window_reject 窗口已满时是否直接拒绝,默认阻塞等待
+tps 本连接提交速率(条/秒),0不限制,账号级限速见 SMS.SetRateLimiter
+burst 允许的突发提交数,默认等于 tps
 */
 func (c *sms_conn) SetExtParam(ext map[string]string) {
 	if ext != nil {
@@ -198,26 +275,85 @@ func (c *sms_conn) SetExtParam(ext map[string]string) {
 		c.checkVer = utils.MapItem(ext, "check_version", 0) == 1
 		c.autoActiveResp = utils.MapItem(ext, "auto_active_resp", 1) == 1
 		c.systemType = utils.MapItem(ext, "system_type", "")
+		c.loginType = utils.MapItem(ext, "login_type", byte(0))
+		c.nodeId = utils.MapItem(ext, "node_id", c.parent.NodeId)
+		c.bindType = enum.BIND_TRX
+		if c.Protocol.Raw() == "smpp" {
+			switch utils.MapItem(ext, "bind_type", "trx") {
+			case "tx":
+				c.bindType = enum.BIND_TX
+			case "rx":
+				c.bindType = enum.BIND_RX
+			}
+		}
+		c.requestTimeout = time.Duration(utils.MapItem(ext, "request_timeout", 30)) * time.Second
+		if size := utils.MapItem(ext, "window_size", 0); size > 0 {
+			timeout := time.Duration(utils.MapItem(ext, "window_timeout", 30)) * time.Second
+			c.window = newWindow(size, timeout, utils.MapItem(ext, "window_reject", 0) == 1)
+		} else {
+			c.window = nil
+		}
+		if tps := utils.MapItem(ext, "tps", 0); tps > 0 {
+			c.limiter = utils.NewLimiter(tps, utils.MapItem(ext, "burst", 0))
+		} else {
+			c.limiter = nil
+		}
 	}
 }
 
 // SendPkt pack the smpp packet structure and send it to the other peer.
 func (c *sms_conn) SendPDU(pdu PDU) error {
+	return c.send(c.ctx, pdu)
+}
+
+// InFlight 已发送未收到响应的请求数
+func (c *sms_conn) InFlight() int {
+	if c.window != nil {
+		return c.window.len()
+	}
+	return c.pending.len()
+}
+
+func (c *sms_conn) send(ctx context.Context, pdu PDU) (err error) {
 	defer func() {
 		if err := recover(); err != nil {
 			logger.Errorln("smpp.send.panic:", err)
 			c.Close()
 		}
 	}()
-	if c.Connected == enum.CONN_CONNING {
+	if atomic.LoadInt32(&c.Connected) == enum.CONN_CONNING {
 		return smserror.ErrConning
 	}
-	if c.Connected == enum.CONN_DISCONNECTED {
+	if atomic.LoadInt32(&c.Connected) == enum.CONN_DISCONNECTED {
 		return smserror.ErrConnIsClosed
 	}
 	if pdu == nil {
 		return smserror.ErrPktIsNil
 	}
+	if windowed(pdu) {
+		// 在校验状态前计数, 进入 BIND_UNBINDING 后 drain 能等到已通过校验的请求
+		atomic.AddInt32(&c.sending, 1)
+		defer atomic.AddInt32(&c.sending, -1)
+	}
+	if err := c.checkBindState(pdu); err != nil {
+		return err
+	}
+	if isSubmit(pdu) {
+		if err := c.throttle(ctx); err != nil {
+			return err
+		}
+	}
+	if w := c.window; w != nil && windowed(pdu) {
+		seq := pdu.GetSequenceNumber()
+		if err := w.acquire(ctx, c.ctx.Done(), seq); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				w.release(seq)
+			}
+		}()
+	}
 
 	// buf := c.pduWriterPool.Get()
 	// defer c.pduWriterPool.Put(buf)
@@ -229,11 +365,13 @@ func (c *sms_conn) SendPDU(pdu PDU) error {
 	default:
 		c.logger.With("send", pdu.GetHeader()).Infof("%x", wr.Bytes())
 	}
-	_, err := c.Conn.Write(wr.Bytes()) //block write
+	_, err = c.Conn.Write(wr.Bytes()) //block write
 	if err != nil {
 		c.Close()
+		return err
 	}
-	return err
+	c.onSent(pdu)
+	return nil
 }
 
 func (c *sms_conn) Logger() *zap.SugaredLogger {
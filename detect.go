@@ -0,0 +1,113 @@
+package zysms
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+// 登录报文长度, cmpp/smgp/sgip 的登录命令都是 1, 按长度区分
+const (
+	cmppConnectLen = 39
+	smgpLoginLen   = 42
+	sgipBindLen    = 61
+	// 识别时最多预读的字节数
+	detectMaxLen = 256
+)
+
+// peekConn 识别协议时预读的数据仍由后续读取返回
+type peekConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *peekConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// NetConn 被包装的连接, 与 tls.Conn 一致
+func (c *peekConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// detectProto 预读第一个报文识别协议, 不消耗数据
+func detectProto(r *bufio.Reader) (codec.SmsProto, error) {
+	head, err := r.Peek(8)
+	if err != nil {
+		return codec.UNKNOWN, err
+	}
+	size := int(binary.BigEndian.Uint32(head))
+	cmd := codec.CommandId(binary.BigEndian.Uint32(head[4:]))
+	if size < 12 || size > detectMaxLen {
+		return codec.UNKNOWN, smserror.ErrInvalidPDU
+	}
+	data, err := r.Peek(size)
+	if err != nil {
+		return codec.UNKNOWN, err
+	}
+	// smpp 绑定报文为变长, 先按结构严格校验
+	if ver, ok := smppBindVersion(cmd, data); ok {
+		switch {
+		case ver >= 0x50:
+			return codec.SMPP50, nil
+		case ver >= 0x34:
+			return codec.SMPP34, nil
+		}
+		return codec.SMPP33, nil
+	}
+	switch {
+	case cmd == cmpp.CMPP_CONNECT && size == cmppConnectLen:
+		switch codec.Version(data[34]) {
+		case cmpp.V30:
+			return codec.CMPP30, nil
+		case cmpp.V21:
+			return codec.CMPP21, nil
+		}
+		return codec.CMPP20, nil
+	case cmd == smgp.SMGP_LOGIN && size == smgpLoginLen:
+		return codec.SMGP30, nil
+	case cmd == sgip.SGIP_BIND && size == sgipBindLen:
+		return codec.SGIP, nil
+	}
+	return codec.UNKNOWN, smserror.ErrProtoNotSupport
+}
+
+// smppBindVersion 校验 smpp bind 报文结构, 返回 interface_version
+func smppBindVersion(cmd codec.CommandId, data []byte) (byte, bool) {
+	switch cmd {
+	case smpp.BIND_RECEIVER, smpp.BIND_TRANSMITTER, smpp.BIND_TRANSCEIVER:
+	default:
+		return 0, false
+	}
+	// command_status 必须为 0
+	if len(data) < 16 || binary.BigEndian.Uint32(data[8:]) != 0 {
+		return 0, false
+	}
+	body := data[16:]
+	// system_id, password, system_type
+	for _, max := range []int{16, 9, 13} {
+		n := bytes.IndexByte(body, 0)
+		if n < 0 || n >= max {
+			return 0, false
+		}
+		body = body[n+1:]
+	}
+	// interface_version, addr_ton, addr_npi
+	if len(body) < 4 {
+		return 0, false
+	}
+	ver := body[0]
+	// address_range 以 0 结尾且为报文最后一个字段
+	if n := bytes.IndexByte(body[3:], 0); n < 0 || n >= 41 || 3+n+1 != len(body) {
+		return 0, false
+	}
+	return ver, true
+}
@@ -0,0 +1,123 @@
+package zysms
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/enum"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+func marshal(p codec.PDU) []byte {
+	w := codec.NewWriter()
+	p.Marshal(w)
+	return w.Bytes()
+}
+
+func TestDetectProto(t *testing.T) {
+	cmppConn := func(ver codec.Version) []byte {
+		p := cmpp.NewConnReq(ver).(*cmpp.ConnReq)
+		p.SrcAddr = testUid
+		p.Secret = testPwd
+		return marshal(p)
+	}
+	smgpLogin := smgp.NewLoginReq(smgp.V30).(*smgp.LoginReq)
+	smgpLogin.ClientID = testUid
+	smgpLogin.Secret = testPwd
+	smgpLogin.Version = smgp.V30
+	sgipBind := sgip.NewBindReq(sgip.V12, 0).(*sgip.BindReq)
+	sgipBind.LoginType = 1
+	sgipBind.LoginName = testUid
+	sgipBind.LoginPassword = testPwd
+	smppBind := func(t smpp.BindingType, ver codec.Version, sysId, pwd, addr string) []byte {
+		p := smpp.NewBindRequest(t)
+		p.SystemID = sysId
+		p.Password = pwd
+		p.SystemType = ""
+		p.InterfaceVersion = ver
+		p.AddressRange.SetAddressRange(addr)
+		return marshal(p)
+	}
+	// 长度与 cmpp 登录相同的 smpp bind_receiver
+	ambiguous := smppBind(smpp.Receiver, smpp.V34, testUid, testPwd, "1234")
+	require.Len(t, ambiguous, cmppConnectLen)
+	// command_status 不为 0 时不是 smpp
+	badStatus := smppBind(smpp.Transceiver, smpp.V34, testUid, testPwd, "")
+	badStatus[11] = 1
+	// 声明长度超过实际数据
+	short := cmppConn(cmpp.V30)[:20]
+	// 长度字段超出识别范围
+	large := []byte{0, 0, 1, 1, 0, 0, 0, 1}
+	// 长度与命令都不匹配
+	unknown := marshal(cmpp.NewActiveTestReq(cmpp.V30))
+
+	tests := []struct {
+		name  string
+		data  []byte
+		proto codec.SmsProto
+		err   error
+	}{
+		{"cmpp20", cmppConn(cmpp.V20), codec.CMPP20, nil},
+		{"cmpp21", cmppConn(cmpp.V21), codec.CMPP21, nil},
+		{"cmpp30", cmppConn(cmpp.V30), codec.CMPP30, nil},
+		{"smgp30", marshal(smgpLogin), codec.SMGP30, nil},
+		{"sgip", marshal(sgipBind), codec.SGIP, nil},
+		{"smpp33", smppBind(smpp.Transmitter, smpp.V33, testUid, testPwd, ""), codec.SMPP33, nil},
+		{"smpp34", smppBind(smpp.Transceiver, smpp.V34, testUid, testPwd, ""), codec.SMPP34, nil},
+		{"smpp50", smppBind(smpp.Receiver, smpp.V50, testUid, testPwd, ""), codec.SMPP50, nil},
+		{"smpp same length as cmpp", ambiguous, codec.SMPP34, nil},
+		{"smpp bad status", badStatus, codec.UNKNOWN, smserror.ErrProtoNotSupport},
+		{"empty", nil, codec.UNKNOWN, io.EOF},
+		{"short header", []byte{0, 0, 0, 39, 0}, codec.UNKNOWN, io.EOF},
+		{"short body", short, codec.UNKNOWN, io.EOF},
+		{"too small", []byte{0, 0, 0, 8, 0, 0, 0, 1}, codec.UNKNOWN, smserror.ErrInvalidPDU},
+		{"too large", large, codec.UNKNOWN, smserror.ErrInvalidPDU},
+		{"unknown", unknown, codec.UNKNOWN, smserror.ErrProtoNotSupport},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(tt.data))
+			proto, err := detectProto(r)
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, tt.proto, proto)
+			if err == nil {
+				// 预读的数据不被消耗
+				data, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.Equal(t, tt.data, data)
+			}
+		})
+	}
+}
+
+func TestListenAuto(t *testing.T) {
+	s := New(codec.AUTO)
+	s.Authenticator = testAuth
+	l, err := s.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownNow(s) })
+
+	for _, proto := range []codec.SmsProto{codec.CMPP20, codec.CMPP30, codec.SMGP30, codec.SGIP, codec.SMPP34} {
+		t.Run(proto.String(), func(t *testing.T) {
+			_, c := testDial(t, proto, l, nil)
+			require.True(t, c.IsAuth)
+			require.Eventually(t, func() bool {
+				for _, sc := range l.Conns().All() {
+					if sc.(*sms_conn).Protocol == proto && sc.BindState() == enum.BIND_TRX {
+						return true
+					}
+				}
+				return false
+			}, time.Second, 5*time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package zysms
+
+import (
+	"context"
+	"maps"
+	"net"
+	"time"
+
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+// SgipEndpoint SGIP SP 端, SP 向 SMG 建立连接提交短信, SMG 另外向 SP 建立连接推送上行及状态报告,
+// 两条连接共用同一个 SMS 的回调及 Receipts, 状态报告与提交关联
+type SgipEndpoint struct {
+	sms      *SMS
+	session  *Session
+	listener *Listener
+	smg      Account
+}
+
+/*
+addr/uid/pwd SP 登录 SMG 的地址及账号, 断开后自动重连
+smgUid/smgPwd SMG 反向登录 SP 的账号, 只接受 LoginType 为 2 的登录
+ext 同 NewSession
+*/
+func (s *SMS) NewSgipEndpoint(addr string, uid, pwd string, smgUid, smgPwd string, timeout time.Duration, ext map[string]string) (*SgipEndpoint, error) {
+	if s.proto != codec.SGIP {
+		return nil, smserror.ErrProtoNotSupport
+	}
+	// 空的 ReportReq 心跳会被 SMG 当作状态报告, 提交连接默认不发送心跳
+	ext = maps.Clone(ext)
+	if ext == nil {
+		ext = map[string]string{}
+	}
+	if _, ok := ext["active_interval"]; !ok {
+		ext["active_interval"] = "0"
+	}
+	return &SgipEndpoint{
+		sms:     s,
+		session: s.NewSession(addr, uid, pwd, timeout, ext),
+		smg:     Account{ID: smgUid, Password: smgPwd},
+	}, nil
+}
+
+// Start 监听 SMG 的反向连接, 并开始连接 SMG
+func (e *SgipEndpoint) Start(listenAddr string) error {
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return err
+	}
+	l, err := newListener(ln, e.sms)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	l.Authenticator = AuthenticatorFunc(e.lookup)
+	l.loginType = 2
+	e.listener = l
+	tryGO(l.serve)
+	e.session.Start()
+	return nil
+}
+
+func (e *SgipEndpoint) lookup(conn Conn, id string) (*Account, error) {
+	if id != e.smg.ID {
+		return nil, nil
+	}
+	return &e.smg, nil
+}
+
+// Session 向 SMG 提交的连接
+func (e *SgipEndpoint) Session() *Session {
+	return e.session
+}
+
+// Listener SMG 反向连接的监听, Start 之前为 nil
+func (e *SgipEndpoint) Listener() *Listener {
+	return e.listener
+}
+
+// SendPDU 通过提交连接发送
+func (e *SgipEndpoint) SendPDU(pdu PDU) error {
+	return e.session.SendPDU(pdu)
+}
+
+// Request 通过提交连接发送并等待响应
+func (e *SgipEndpoint) Request(ctx context.Context, pdu PDU) (PDU, error) {
+	return e.session.Request(ctx, pdu)
+}
+
+// SendText 通过提交连接发送短信, 状态报告从反向连接到达后回调 SMS.OnReport
+func (e *SgipEndpoint) SendText(ctx context.Context, msg Message) ([]string, error) {
+	c := e.session.Conn()
+	if c == nil {
+		return nil, smserror.ErrSessionDown
+	}
+	return c.SendText(ctx, msg)
+}
+
+// Close 关闭提交连接及监听
+func (e *SgipEndpoint) Close() {
+	if e.listener != nil {
+		e.listener.Close()
+	}
+	e.session.Close()
+}
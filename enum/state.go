@@ -8,6 +8,31 @@ const (
 	CONN_AUTHOK
 )
 
+// Bind States
+const (
+	BIND_OPEN int32 = iota
+	BIND_TX
+	BIND_RX
+	BIND_TRX
+	BIND_UNBINDING
+	BIND_CLOSED
+)
+
+// Session States
+const (
+	SESSION_CONNECTING int32 = iota
+	SESSION_BOUND
+	SESSION_BACKOFF
+	SESSION_CLOSED
+)
+
+// Pool Strategies
+const (
+	POOL_ROUND_ROBIN int32 = iota
+	POOL_LEAST_INFLIGHT
+	POOL_LOWEST_DELAY
+)
+
 // Stat
 const (
 	REPORT_DELIVERED = "DELIVRD"
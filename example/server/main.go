@@ -1,8 +1,6 @@
 package main
 
 import (
-	"bytes"
-	"crypto/md5"
 	"log"
 	"os"
 	"os/signal"
@@ -11,8 +9,6 @@ import (
 	"github.com/zhiyin2021/zysms"
 	"github.com/zhiyin2021/zysms/cmpp"
 	"github.com/zhiyin2021/zysms/codec"
-	"github.com/zhiyin2021/zysms/smserror"
-	"github.com/zhiyin2021/zysms/utils"
 	"github.com/zhiyin2021/zysms/utils/logger"
 )
 
@@ -34,14 +30,19 @@ func main() {
 	sms.OnError = func(c zysms.Conn, err error) {
 		c.Logger().Errorln("server: error: ", err)
 	}
+	// 登录由库校验, 登录成功前的报文不会进入 OnRecv
+	sms.Authenticator = zysms.AuthenticatorFunc(func(c zysms.Conn, id string) (*zysms.Account, error) {
+		if id != userS {
+			return nil, nil
+		}
+		return &zysms.Account{ID: userS, Password: passwordS}, nil
+	})
 	sms.OnRecv = func(conn zysms.Conn, req zysms.PDU) {
 		var err error
 		var resp zysms.PDU
 		switch req := req.(type) {
 		case *cmpp.SubmitReq:
 			resp, err = handleSubmit(conn, req)
-		case *cmpp.ConnReq:
-			resp, err = handleLogin(conn, req)
 		default:
 			conn.Logger().Infof("event %T", req)
 		}
@@ -68,35 +69,6 @@ func main() {
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 	<-sig
 }
-func handleLogin(conn zysms.Conn, req *cmpp.ConnReq) (codec.PDU, error) {
-	resp := req.GetResponse().(*cmpp.ConnResp)
-	if req.SrcAddr != utils.OctetString(userS, 6) {
-		resp.Status = uint32(smserror.ErrnoConnInvalidSrcAddr)
-		return resp, smserror.ConnRspStatusErrMap[smserror.ErrnoConnInvalidSrcAddr]
-	}
-
-	tm := req.Timestamp
-	authSrc := md5.Sum(bytes.Join([][]byte{[]byte(utils.OctetString(userS, 6)),
-		make([]byte, 9),
-		[]byte(passwordS),
-		[]byte(utils.Timestamp2Str(tm))},
-		nil))
-
-	if req.AuthSrc != string(authSrc[:]) {
-		// conn.Logger().Errorln("handleLogin error: ", cmpp.ConnRspStatusErrMap[smserror.ErrnoConnAuthFailed])
-		resp.Status = uint32(smserror.ErrnoConnAuthFailed)
-		return resp, smserror.ConnRspStatusErrMap[smserror.ErrnoConnAuthFailed]
-	}
-
-	authIsmg := md5.Sum(bytes.Join([][]byte{{byte(resp.Status)},
-		authSrc[:],
-		[]byte(passwordS)},
-		nil))
-	resp.AuthIsmg = string(authIsmg[:])
-	conn.SetExtParam(map[string]string{"node_id": "123456"})
-	return resp, nil
-}
-
 func handleSubmit(conn zysms.Conn, req *cmpp.SubmitReq) (codec.PDU, error) {
 	resp := req.GetResponse().(*cmpp.SubmitResp)
 	resp.MsgId = 12878564852733378560
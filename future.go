@@ -0,0 +1,179 @@
+package zysms
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+// Future 异步请求结果,响应到达、超时或连接关闭时完成
+type Future struct {
+	seq  int32
+	done chan struct{}
+	once sync.Once
+	// mu 保护 timer, 发送后才开始计时, 可能与 complete 并发
+	mu    sync.Mutex
+	timer *time.Timer
+	resp  PDU
+	err   error
+	// onResp 在接收协程中处理响应, 先于后续报文
+	onResp func(PDU)
+}
+
+func newFuture(seq int32) *Future {
+	return &Future{seq: seq, done: make(chan struct{})}
+}
+
+// Done 请求完成时关闭
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result 阻塞等待响应
+func (f *Future) Result() (PDU, error) {
+	<-f.done
+	return f.resp, f.err
+}
+
+// Wait 等待响应,ctx 先结束时返回 ctx.Err()
+func (f *Future) Wait(ctx context.Context) (PDU, error) {
+	select {
+	case <-f.done:
+		return f.resp, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *Future) complete(resp PDU, err error) {
+	f.once.Do(func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.timer != nil {
+			f.timer.Stop()
+		}
+		f.resp, f.err = resp, err
+		close(f.done)
+	})
+}
+
+// startTimer 发送成功后开始计时, 已完成时不再计时
+func (f *Future) startTimer(d time.Duration, fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-f.done:
+	default:
+		f.timer = time.AfterFunc(d, fn)
+	}
+}
+
+// pendingTable 等待响应的请求,按序列号索引
+type pendingTable struct {
+	mu    sync.Mutex
+	items map[int32]*Future
+}
+
+func newPendingTable() *pendingTable {
+	return &pendingTable{items: map[int32]*Future{}}
+}
+
+func (t *pendingTable) add(f *Future) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.items[f.seq] = f
+}
+
+func (t *pendingTable) remove(seq int32) *Future {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if f, ok := t.items[seq]; ok {
+		delete(t.items, seq)
+		return f
+	}
+	return nil
+}
+
+func (t *pendingTable) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.items)
+}
+
+// failAll 连接关闭时,所有等待者返回 err
+func (t *pendingTable) failAll(err error) {
+	t.mu.Lock()
+	items := t.items
+	t.items = map[int32]*Future{}
+	t.mu.Unlock()
+	for _, f := range items {
+		f.complete(nil, err)
+	}
+}
+
+// SendAsync 发送请求并返回 Future,响应按序列号匹配
+func (c *sms_conn) SendAsync(pdu PDU) (*Future, error) {
+	return c.sendAsync(c.ctx, pdu, nil)
+}
+
+func (c *sms_conn) sendAsync(ctx context.Context, pdu PDU, onResp func(PDU)) (*Future, error) {
+	if pdu == nil {
+		return nil, smserror.ErrPktIsNil
+	}
+	f := newFuture(pdu.GetSequenceNumber())
+	f.onResp = onResp
+	c.pending.add(f)
+	if err := c.send(ctx, pdu); err != nil {
+		c.pending.remove(f.seq)
+		return nil, err
+	}
+	if c.requestTimeout > 0 {
+		f.startTimer(c.requestTimeout, func() {
+			if c.pending.remove(f.seq) == f {
+				f.complete(nil, smserror.ErrRequestTimeout)
+			}
+		})
+	}
+	return f, nil
+}
+
+// Request 发送请求并等待对应的响应
+func (c *sms_conn) Request(ctx context.Context, pdu PDU) (PDU, error) {
+	f, err := c.sendAsync(ctx, pdu, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.wait(ctx, f)
+}
+
+// wait 等待响应, ctx 先结束时移除等待中的请求
+func (c *sms_conn) wait(ctx context.Context, f *Future) (PDU, error) {
+	resp, err := f.Wait(ctx)
+	if err != nil && ctx.Err() != nil {
+		if c.pending.remove(f.seq) == f {
+			f.complete(nil, err)
+		}
+	}
+	return resp, err
+}
+
+// resolve 将响应交给等待中的请求,返回 true 表示已被消费
+func (c *sms_conn) resolve(pdu PDU) bool {
+	if !isResponse(pdu) {
+		return false
+	}
+	if f := c.pending.remove(pdu.GetSequenceNumber()); f != nil {
+		if f.onResp != nil {
+			f.onResp(pdu)
+		}
+		f.complete(pdu, nil)
+		return true
+	}
+	return false
+}
+
+func isResponse(pdu PDU) bool {
+	return pdu.GetHeader().GetCommandID()&0x80000000 != 0
+}
@@ -0,0 +1,82 @@
+package zysms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+func TestRequestCorrelation(t *testing.T) {
+	c, peer := testPipe(t, codec.CMPP30)
+	reqs := readPDUs(peer, 2)
+
+	f1, err := c.SendAsync(cmpp.NewSubmitReq(cmpp.V30))
+	require.NoError(t, err)
+	f2, err := c.SendAsync(cmpp.NewSubmitReq(cmpp.V30))
+	require.NoError(t, err)
+	require.Equal(t, 2, c.InFlight())
+
+	// 按相反顺序响应, 仍按序列号交给对应的请求
+	p1, p2 := <-reqs, <-reqs
+	for i, p := range []codec.PDU{p2, p1} {
+		resp := p.GetResponse().(*cmpp.SubmitResp)
+		resp.MsgId = uint64(i + 1)
+		writePDU(t, peer, resp)
+	}
+	r1, err := f1.Result()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), r1.(*cmpp.SubmitResp).MsgId)
+	r2, err := f2.Result()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), r2.(*cmpp.SubmitResp).MsgId)
+	require.Equal(t, 0, c.InFlight())
+}
+
+func TestRequestTimeout(t *testing.T) {
+	c, peer := testPipe(t, codec.CMPP30)
+	c.requestTimeout = 50 * time.Millisecond
+	reqs := readPDUs(peer, 1)
+
+	_, err := c.Request(context.Background(), cmpp.NewSubmitReq(cmpp.V30))
+	require.ErrorIs(t, err, smserror.ErrRequestTimeout)
+	require.Equal(t, 0, c.InFlight())
+
+	// 超时后到达的响应不再被消费
+	resp := (<-reqs).GetResponse()
+	require.False(t, c.resolve(resp))
+}
+
+func TestRequestContext(t *testing.T) {
+	c, peer := testPipe(t, codec.CMPP30)
+	readPDUs(peer, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := c.Request(ctx, cmpp.NewSubmitReq(cmpp.V30))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 0, c.InFlight())
+}
+
+func TestRequestClose(t *testing.T) {
+	c, peer := testPipe(t, codec.CMPP30)
+	readPDUs(peer, 1)
+
+	f, err := c.SendAsync(cmpp.NewSubmitReq(cmpp.V30))
+	require.NoError(t, err)
+	c.close()
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("future not completed on close")
+	}
+	_, err = f.Result()
+	require.ErrorIs(t, err, smserror.ErrConnIsClosed)
+
+	_, err = c.SendAsync(cmpp.NewSubmitReq(cmpp.V30))
+	require.ErrorIs(t, err, smserror.ErrConnIsClosed)
+}
@@ -0,0 +1,133 @@
+package zysms
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+// Message 待发送的短信, 超长时自动拆分
+type Message struct {
+	From string
+	To   []string
+	Text string
+	// Encoding 为空时自动选择, 含中文等宽字符时使用 UCS2
+	Encoding      codec.Encoding
+	RequestReport bool
+}
+
+// SendText 按协议拆分、编码并提交短信, 等待所有分片响应后返回网关消息ID
+func (c *sms_conn) SendText(ctx context.Context, msg Message) ([]string, error) {
+	if len(msg.To) == 0 {
+		return nil, smserror.ErrInvalidPDU
+	}
+	pdus, err := c.action.submit(&msg)
+	if err != nil {
+		return nil, err
+	}
+	// 需要状态报告时记录提交, 在收到响应时登记, 保证先于状态报告
+	var rec *Receipt
+	if msg.RequestReport && c.parent.Receipts != nil {
+		rec = &Receipt{Account: c.account, From: msg.From, To: msg.To, Text: msg.Text, Time: time.Now(), Total: len(pdus)}
+	}
+	futures := make([]*Future, 0, len(pdus))
+	for _, p := range pdus {
+		var onResp func(PDU)
+		if rec != nil {
+			onResp = func(resp PDU) {
+				if id, err := c.action.submitResult(resp); err == nil {
+					if err = c.parent.addSegment(rec, id, p); err != nil {
+						c.parent.doError(c, err)
+					}
+				}
+			}
+		}
+		f, err := c.sendAsync(ctx, p, onResp)
+		if err != nil {
+			if rec != nil {
+				c.parent.endReceipt(rec)
+			}
+			return nil, err
+		}
+		futures = append(futures, f)
+	}
+	ids := make([]string, 0, len(futures))
+	for i, f := range futures {
+		resp, err := c.wait(ctx, f)
+		if err == nil {
+			var id string
+			if id, err = c.action.submitResult(resp); err == nil {
+				ids = append(ids, id)
+				continue
+			}
+		}
+		// 不再等待其余分片的响应
+		for _, f := range futures[i+1:] {
+			if c.pending.remove(f.seq) == f {
+				f.complete(nil, err)
+			}
+		}
+		if rec != nil {
+			c.parent.endReceipt(rec)
+		}
+		return ids, err
+	}
+	return ids, nil
+}
+
+// Cancel 删除网关中已提交未下发的短信, msgId 为 SendText 返回的消息ID
+func (c *sms_conn) Cancel(ctx context.Context, msgId string) error {
+	if c.Protocol.Raw() != "cmpp" {
+		return fmt.Errorf("%w: 删除短信仅支持 cmpp [ %s ]", smserror.ErrProtoNotSupport, c.Protocol)
+	}
+	id, err := strconv.ParseUint(msgId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: cmpp 消息ID应为数字 [ %s ]", smserror.ErrMethodParamsInvalid, msgId)
+	}
+	req := cmpp.NewCancelReq(c.Typ).(*cmpp.CancelReq)
+	req.MsgId = id
+	resp, err := c.Request(ctx, req)
+	if err != nil {
+		return err
+	}
+	p, ok := resp.(*cmpp.CancelResp)
+	if !ok {
+		return smserror.ErrRespNotMatch
+	}
+	if p.SuccessId != 0 {
+		return smserror.ErrCancelFailed
+	}
+	return nil
+}
+
+// handleCancel cmpp 删除短信请求由 OnCancel 处理并回复, 不再交给 OnRecv; 都未设置时回复失败
+func (c *sms_conn) handleCancel(pdu PDU) bool {
+	p, ok := pdu.(*cmpp.CancelReq)
+	if !ok || (c.parent.OnCancel == nil && c.parent.OnRecv != nil) {
+		return false
+	}
+	resp := p.GetResponse().(*cmpp.CancelResp)
+	resp.SuccessId = 1
+	if c.parent.OnCancel != nil && c.parent.OnCancel(c, strconv.FormatUint(p.MsgId, 10)) {
+		resp.SuccessId = 0
+	}
+	c.SendPDU(resp)
+	return true
+}
+
+// splitText cmpp/smgp/sgip 使用的拆分, 未指定编码时 ASCII 或 UCS2
+func splitText(msg *Message) ([]*codec.ShortMessage, error) {
+	enc := msg.Encoding
+	if enc == nil {
+		enc = codec.ASCII0
+		if codec.HasWidthChar(msg.Text) {
+			enc = codec.UCS2
+		}
+	}
+	return codec.NewLongMessageWithEncoding(msg.Text, enc)
+}
@@ -0,0 +1,131 @@
+package zysms
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+)
+
+// submitPart 提交请求中的分片信息: udhi 标志, 包总数/序号(协议不带时为 0), UDH 中的总数/序号
+type submitPart struct {
+	udhi                bool
+	pkTotal, pkNumber   byte
+	udhTotal, udhNumber byte
+}
+
+func partOf(t *testing.T, pdu codec.PDU) (v submitPart) {
+	t.Helper()
+	switch p := pdu.(type) {
+	case *cmpp.SubmitReq:
+		v.udhi, v.pkTotal, v.pkNumber = p.TpUdhi == 1, p.PkTotal, p.PkNumber
+		v.udhTotal, v.udhNumber, _, _ = p.Message.GetConcatInfo()
+	case *smgp.SubmitReq:
+		v.udhi = p.TpUdhi()
+		v.pkTotal, _ = p.PkTotal()
+		v.pkNumber, _ = p.PkNumber()
+		v.udhTotal, v.udhNumber, _, _ = p.Message.GetConcatInfo()
+	case *sgip.SubmitReq:
+		v.udhi = p.TpUdhi == 1
+		v.udhTotal, v.udhNumber, _, _ = p.Message.GetConcatInfo()
+	case *smpp.SubmitSM:
+		v.udhi = p.EsmClass&smpp.SM_UDH_GSM != 0
+		v.udhTotal, v.udhNumber, _, _ = p.Message.GetConcatInfo()
+	default:
+		t.Fatalf("unexpected %T", pdu)
+	}
+	return
+}
+
+func TestSubmitSplit(t *testing.T) {
+	// 单条上限: ASCII/GSM7 160 字符, UCS2 70 字符
+	texts := []struct {
+		name  string
+		text  string
+		parts int
+	}{
+		{"gsm7 single", strings.Repeat("a", 160), 1},
+		{"gsm7 long", strings.Repeat("a", 161), 2},
+		{"ucs2 single", strings.Repeat("测", 70), 1},
+		{"ucs2 long", strings.Repeat("测", 71), 2},
+	}
+	for _, proto := range []codec.SmsProto{codec.CMPP30, codec.SMGP30, codec.SGIP, codec.SMPP34} {
+		c, _ := testPipe(t, proto)
+		for _, tt := range texts {
+			t.Run(proto.String()+"/"+tt.name, func(t *testing.T) {
+				pdus, err := c.action.submit(&Message{From: "10690001", To: []string{receiptDest}, Text: tt.text})
+				require.NoError(t, err)
+				require.Len(t, pdus, tt.parts)
+				for i, p := range pdus {
+					v := partOf(t, p)
+					if tt.parts == 1 {
+						require.False(t, v.udhi)
+						require.Zero(t, v.udhTotal)
+						continue
+					}
+					require.True(t, v.udhi)
+					require.Equal(t, byte(tt.parts), v.udhTotal)
+					require.Equal(t, byte(i+1), v.udhNumber)
+					switch proto {
+					case codec.CMPP30, codec.SMGP30:
+						require.Equal(t, byte(tt.parts), v.pkTotal)
+						require.Equal(t, byte(i+1), v.pkNumber)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSmppSubmitRecipients(t *testing.T) {
+	c, _ := testPipe(t, codec.SMPP34)
+	to := []string{"13300000001", "13300000002", "13300000003"}
+	pdus, err := c.action.submit(&Message{From: "10690001", To: to, Text: strings.Repeat("a", 161)})
+	require.NoError(t, err)
+	// 每个号码各提交全部分片
+	require.Len(t, pdus, 2*len(to))
+	for i, p := range pdus {
+		sm := p.(*smpp.SubmitSM)
+		require.Equal(t, to[i/2], sm.DestAddr.Address())
+		require.Equal(t, byte(i%2+1), partOf(t, p).udhNumber)
+	}
+}
+
+func TestSendTextCancel(t *testing.T) {
+	c, peer := testPipe(t, codec.CMPP30)
+	submits := readPDUs(peer, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	type result struct {
+		ids []string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ids, err := c.SendText(ctx, Message{From: "10690001", To: []string{receiptDest}, Text: strings.Repeat("测", 200)})
+		done <- result{ids, err}
+	}()
+
+	// 只响应第一个分片后取消
+	var reqs []*cmpp.SubmitReq
+	for p := range submits {
+		reqs = append(reqs, p.(*cmpp.SubmitReq))
+	}
+	require.Len(t, reqs, 3)
+	submitResp(t, peer, reqs[0], 1)
+	require.Eventually(t, func() bool { return c.pending.len() == 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+
+	r := <-done
+	require.ErrorIs(t, r.err, context.Canceled)
+	require.Equal(t, []string{"1"}, r.ids)
+	// 取消后不再等待其余分片的响应
+	require.Zero(t, c.pending.len())
+}
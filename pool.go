@@ -0,0 +1,140 @@
+package zysms
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhiyin2021/zysms/enum"
+	"github.com/zhiyin2021/zysms/smserror"
+	"github.com/zhiyin2021/zysms/utils"
+)
+
+// Pool 多链路客户端,每条链路为一个自动重连的 Session,发送时按策略选择已登录的链路
+type Pool struct {
+	sessions []*Session
+	strategy int32
+	next     uint32
+}
+
+/*
+addrs 服务地址,链路按顺序轮流分配到各地址
+size 链路数量
+pool_strategy 选择策略 enum.POOL_*, 0轮询 1最少在途 2最低心跳延时
+其余参数同 NewSession
+*/
+func (s *SMS) NewPool(addrs []string, size int, uid, pwd string, timeout time.Duration, ext map[string]string) *Pool {
+	p := &Pool{strategy: utils.MapItem(ext, "pool_strategy", enum.POOL_ROUND_ROBIN)}
+	if len(addrs) == 0 {
+		return p
+	}
+	for i := 0; i < size; i++ {
+		p.sessions = append(p.sessions, s.NewSession(addrs[i%len(addrs)], uid, pwd, timeout, ext))
+	}
+	return p
+}
+
+// Sessions 所有链路,可用于设置 OnStateChange
+func (p *Pool) Sessions() []*Session {
+	return p.sessions
+}
+
+// Start 启动所有链路
+func (p *Pool) Start() {
+	for _, s := range p.sessions {
+		s.Start()
+	}
+}
+
+// Close 关闭所有链路
+func (p *Pool) Close() {
+	for _, s := range p.sessions {
+		s.Close()
+	}
+}
+
+// Conns 当前已登录的连接
+func (p *Pool) Conns() []Conn {
+	conns := make([]Conn, 0, len(p.sessions))
+	for _, s := range p.sessions {
+		if c := s.Conn(); c != nil {
+			conns = append(conns, c)
+		}
+	}
+	return conns
+}
+
+// Pick 按策略选择一个已登录的连接,均未登录时返回 nil
+func (p *Pool) Pick() Conn {
+	conns := p.Conns()
+	if len(conns) == 0 {
+		return nil
+	}
+	switch p.strategy {
+	case enum.POOL_LEAST_INFLIGHT:
+		return pickBy(conns, func(c Conn) int64 { return int64(c.InFlight()) })
+	case enum.POOL_LOWEST_DELAY:
+		return pickBy(conns, avgDelay)
+	}
+	n := atomic.AddUint32(&p.next, 1)
+	return conns[int(n-1)%len(conns)]
+}
+
+// SendPDU 选择链路发送,无可用链路时交给某个 Session 按 queue_size 排队或拒绝
+func (p *Pool) SendPDU(pdu PDU) error {
+	if c := p.Pick(); c != nil {
+		return c.SendPDU(pdu)
+	}
+	if s := p.fallback(); s != nil {
+		return s.SendPDU(pdu)
+	}
+	return smserror.ErrSessionClosed
+}
+
+// Request 选择链路发送请求并等待响应
+func (p *Pool) Request(ctx context.Context, pdu PDU) (PDU, error) {
+	if c := p.Pick(); c != nil {
+		return c.Request(ctx, pdu)
+	}
+	if s := p.fallback(); s != nil {
+		return s.Request(ctx, pdu)
+	}
+	return nil, smserror.ErrSessionClosed
+}
+
+func (p *Pool) fallback() *Session {
+	if len(p.sessions) == 0 {
+		return nil
+	}
+	n := atomic.AddUint32(&p.next, 1)
+	return p.sessions[int(n-1)%len(p.sessions)]
+}
+
+func pickBy(conns []Conn, score func(Conn) int64) Conn {
+	best, min := conns[0], score(conns[0])
+	for _, c := range conns[1:] {
+		if n := score(c); n < min {
+			best, min = c, n
+		}
+	}
+	return best
+}
+
+// avgDelay 心跳平均延时(微秒),超时按1秒计
+func avgDelay(c Conn) int64 {
+	var sum, n int64
+	for _, d := range c.Delay() {
+		switch {
+		case d < 0:
+			sum += int64(time.Second / time.Microsecond)
+			n++
+		case d > 0:
+			sum += d
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / n
+}
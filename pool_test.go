@@ -0,0 +1,111 @@
+package zysms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+// testPool 连接 l 的 size 条链路, 等待全部登录
+func testPool(t *testing.T, l *Listener, size int, ext map[string]string) *Pool {
+	t.Helper()
+	s := New(codec.CMPP30)
+	p := s.NewPool([]string{l.Addr().String()}, size, testUid, testPwd, time.Second, ext)
+	p.Start()
+	t.Cleanup(p.Close)
+	require.Eventually(t, func() bool { return len(p.Conns()) == size }, 3*time.Second, 5*time.Millisecond)
+	return p
+}
+
+func TestPoolPick(t *testing.T) {
+	_, l := testListen(t, codec.CMPP30)
+	p := testPool(t, l, 3, nil)
+
+	// 轮询使用每条链路
+	picked := map[Conn]int{}
+	for i := 0; i < 6; i++ {
+		picked[p.Pick()]++
+	}
+	require.Len(t, picked, 3)
+	for _, n := range picked {
+		require.Equal(t, 2, n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := p.Request(ctx, cmpp.NewActiveTestReq(cmpp.V30))
+	require.NoError(t, err)
+	require.IsType(t, &cmpp.ActiveTestResp{}, resp)
+}
+
+func TestPoolLeastInflight(t *testing.T) {
+	srv, l := testListen(t, codec.CMPP30)
+	// 不回复提交, 请求一直在途
+	srv.OnRecv = func(c Conn, p PDU) {}
+	p := testPool(t, l, 2, map[string]string{"pool_strategy": "1"})
+	conns := p.Conns()
+
+	submit := func(c Conn) {
+		_, err := c.SendAsync(cmpp.NewSubmitReq(cmpp.V30))
+		require.NoError(t, err)
+	}
+	submit(conns[0])
+	submit(conns[0])
+	submit(conns[1])
+	// 都有在途请求时选择最少的
+	require.Equal(t, 1, conns[1].InFlight())
+	require.Same(t, conns[1], p.Pick())
+}
+
+func TestPoolNoConn(t *testing.T) {
+	// 无可用链路时按 queue_size 排队或拒绝
+	s := New(codec.CMPP30)
+	p := s.NewPool([]string{"127.0.0.1:1"}, 2, testUid, testPwd, 100*time.Millisecond, nil)
+	p.Start()
+	t.Cleanup(p.Close)
+	require.Nil(t, p.Pick())
+	require.ErrorIs(t, p.SendPDU(cmpp.NewActiveTestReq(cmpp.V30)), smserror.ErrSessionDown)
+
+	q := s.NewPool([]string{"127.0.0.1:1"}, 2, testUid, testPwd, 100*time.Millisecond, map[string]string{"queue_size": "1"})
+	q.Start()
+	t.Cleanup(q.Close)
+	require.NoError(t, q.SendPDU(cmpp.NewActiveTestReq(cmpp.V30)))
+}
+
+func TestPoolDropDeadConn(t *testing.T) {
+	_, l := testListen(t, codec.CMPP30)
+	p := testPool(t, l, 2, nil)
+	dead := p.Conns()[0]
+	dead.(*sms_conn).close()
+
+	// 断开的链路不再被选择, 重连后恢复
+	require.Eventually(t, func() bool { return len(p.Conns()) == 1 }, time.Second, 5*time.Millisecond)
+	for i := 0; i < 4; i++ {
+		require.NotSame(t, dead, p.Pick())
+	}
+	require.Eventually(t, func() bool { return len(p.Conns()) == 2 }, 3*time.Second, 10*time.Millisecond)
+	for _, c := range p.Conns() {
+		require.NotSame(t, dead, c)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	_, l := testListen(t, codec.CMPP30)
+	p := testPool(t, l, 2, nil)
+	conns := p.Conns()
+	p.Close()
+
+	require.Empty(t, p.Conns())
+	require.Nil(t, p.Pick())
+	for _, c := range conns {
+		require.False(t, c.IsConnected())
+	}
+	require.ErrorIs(t, p.SendPDU(cmpp.NewActiveTestReq(cmpp.V30)), smserror.ErrSessionClosed)
+	_, err := p.Request(context.Background(), cmpp.NewActiveTestReq(cmpp.V30))
+	require.ErrorIs(t, err, smserror.ErrSessionClosed)
+}
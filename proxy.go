@@ -0,0 +1,106 @@
+package zysms
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// proxyV2Sig PROXY protocol v2 头的签名
+var proxyV2Sig = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyConn 经 PROXY protocol 转发的连接, RemoteAddr 为头中的客户端地址
+type proxyConn struct {
+	peekConn
+	remote net.Addr
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// readProxyHeader 读取 PROXY protocol v1/v2 头, LOCAL 或 UNKNOWN 时返回 nil 地址
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	sig, err := r.Peek(len(proxyV2Sig))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(sig, proxyV2Sig) {
+		return readProxyV2(r)
+	}
+	if string(sig[:6]) == "PROXY " {
+		return readProxyV1(r)
+	}
+	return nil, fmt.Errorf("proxy protocol 头错误")
+}
+
+// readProxyV1 PROXY TCP4 源地址 目的地址 源端口 目的端口\r\n, 最长 107 字节
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < 107 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("proxy protocol v1 头错误")
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("proxy protocol v1 头错误")
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, fmt.Errorf("proxy protocol v1 地址错误")
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyV2 签名 12 字节, 版本/命令 1 字节, 地址族 1 字节, 长度 2 字节, 地址及 TLV
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	head := make([]byte, 16)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+	if head[12]>>4 != 2 {
+		return nil, fmt.Errorf("proxy protocol v2 版本错误")
+	}
+	body := make([]byte, binary.BigEndian.Uint16(head[14:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	// LOCAL 为代理自身的健康检查等, 使用实际地址
+	if head[12]&0x0f == 0 {
+		return nil, nil
+	}
+	switch head[13] >> 4 {
+	case 1: // AF_INET
+		if len(body) < 12 {
+			return nil, fmt.Errorf("proxy protocol v2 地址错误")
+		}
+		return &net.TCPAddr{IP: net.IP(body[:4]), Port: int(binary.BigEndian.Uint16(body[8:]))}, nil
+	case 2: // AF_INET6
+		if len(body) < 36 {
+			return nil, fmt.Errorf("proxy protocol v2 地址错误")
+		}
+		return &net.TCPAddr{IP: net.IP(body[:16]), Port: int(binary.BigEndian.Uint16(body[32:]))}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,74 @@
+package zysms
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/utils"
+)
+
+// SetRateLimiter 设置账号的提交速率(条/秒),同一账号登录的多个连接共享, tps<=0 取消限制
+func (s *SMS) SetRateLimiter(account string, tps, burst int) {
+	if tps <= 0 {
+		s.limiters.Delete(account)
+		return
+	}
+	if v, ok := s.limiters.Load(account); ok {
+		v.(*utils.Limiter).SetRate(tps, burst)
+		return
+	}
+	s.limiters.Store(account, utils.NewLimiter(tps, burst))
+}
+
+func (s *SMS) rateLimiter(account string) *utils.Limiter {
+	if v, ok := s.limiters.Load(account); ok {
+		return v.(*utils.Limiter)
+	}
+	return nil
+}
+
+// throttle 提交类报文发送前按连接及账号限速, 同时预占两者的令牌,
+// ctx 结束时全部归还, 避免一方的令牌被白白消耗
+func (c *sms_conn) throttle(ctx context.Context) error {
+	limiters := make([]*utils.Limiter, 0, 2)
+	if c.limiter != nil {
+		limiters = append(limiters, c.limiter)
+	}
+	if c.account != "" {
+		if l := c.parent.rateLimiter(c.account); l != nil {
+			limiters = append(limiters, l)
+		}
+	}
+	var wait time.Duration
+	for _, l := range limiters {
+		wait = max(wait, l.Reserve())
+	}
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		for _, l := range limiters {
+			l.Cancel()
+		}
+		return ctx.Err()
+	}
+}
+
+// isSubmit 需要限速的提交类报文,心跳等其他报文不计
+func isSubmit(pdu PDU) bool {
+	switch pdu.(type) {
+	case *cmpp.SubmitReq, *smgp.SubmitReq, *smgp.ForwardReq, *sgip.SubmitReq,
+		*smpp.SubmitSM, *smpp.SubmitMulti, *smpp.DataSM, *smpp.BroadcastSM:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package zysms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/utils"
+)
+
+func TestRateLimitSharedAccount(t *testing.T) {
+	s := New(codec.CMPP30)
+	s.SetRateLimiter(testUid, 20, 1)
+	c1, peer1 := testPipeOf(t, s)
+	c2, peer2 := testPipeOf(t, s)
+	c1.account, c2.account = testUid, testUid
+	readPDUs(peer1, 2)
+	readPDUs(peer2, 2)
+
+	// 两个连接共用账号的令牌, 第二条等待补充
+	start := time.Now()
+	require.NoError(t, c1.SendPDU(cmpp.NewSubmitReq(cmpp.V30)))
+	require.NoError(t, c2.SendPDU(cmpp.NewSubmitReq(cmpp.V30)))
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	// 其他账号不受影响
+	c2.account = "900002"
+	start = time.Now()
+	require.NoError(t, c2.SendPDU(cmpp.NewSubmitReq(cmpp.V30)))
+	require.Less(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestRateLimitCancel(t *testing.T) {
+	s := New(codec.CMPP30)
+	s.SetRateLimiter(testUid, 1, 1)
+	c, peer := testPipeOf(t, s)
+	c.account = testUid
+	c.limiter = utils.NewLimiter(1, 2)
+	readPDUs(peer, 1)
+
+	require.NoError(t, c.SendPDU(cmpp.NewSubmitReq(cmpp.V30)))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := c.Request(ctx, cmpp.NewSubmitReq(cmpp.V30))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	// 等待账号令牌时取消, 连接的令牌归还
+	require.True(t, c.limiter.Allow())
+	require.False(t, c.limiter.Allow())
+}
@@ -0,0 +1,219 @@
+package zysms
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/utils/cache"
+)
+
+// LongMessage 合并后的长短信
+type LongMessage struct {
+	Src   string
+	Dest  string
+	Ref   uint16
+	Total int
+	// Parts 按分片序号排列的原始报文, 超时未收齐时缺失的分片为 nil
+	Parts []PDU
+	// Data 合并后的内容, 不含 UDH
+	Data []byte
+	Text string
+
+	enc  codec.Encoding
+	data [][]byte
+	got  int
+	// done 已收齐返回或已超时回调
+	done bool
+}
+
+// Reassembler 长短信合并, 按(源号码, 目的号码, 参考号)缓存分片, 收齐后合并为一条
+type Reassembler struct {
+	// OnExpire 超时未收齐时回调
+	OnExpire func(*LongMessage)
+
+	timeout time.Duration
+	mu      sync.Mutex
+	cache   *cache.Memory
+	stop    func()
+}
+
+// NewReassembler timeout 为第一个分片到达后等待其余分片的时间
+func NewReassembler(timeout time.Duration) *Reassembler {
+	ctx, stop := context.WithCancel(context.Background())
+	return &Reassembler{
+		timeout: timeout,
+		cache:   cache.NewMemory(ctx),
+		stop:    stop,
+	}
+}
+
+// Add 加入上行短信, 支持 cmpp/smgp/sgip DeliverReq 及 smpp DeliverSM
+// 收齐或不是长短信时返回完整消息及 true, 状态报告等其他报文返回 nil, false
+func (r *Reassembler) Add(pdu PDU) (*LongMessage, bool) {
+	p, ok := parsePart(pdu)
+	if !ok {
+		return nil, false
+	}
+	if p.total <= 1 || p.num < 1 || p.num > p.total {
+		msg := &LongMessage{Src: p.src, Dest: p.dst, Ref: p.ref, Total: 1, Parts: []PDU{pdu}, enc: p.enc, data: [][]byte{p.data}, got: 1}
+		msg.join()
+		return msg, true
+	}
+
+	key := fmt.Sprintf("%s|%s|%d|%d", p.src, p.dst, p.ref, p.total)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var msg *LongMessage
+	if v := r.cache.Get(key); v != nil {
+		msg = v.(*LongMessage)
+	} else {
+		msg = &LongMessage{
+			Src:   p.src,
+			Dest:  p.dst,
+			Ref:   p.ref,
+			Total: int(p.total),
+			Parts: make([]PDU, p.total),
+			enc:   p.enc,
+			data:  make([][]byte, p.total),
+		}
+		r.cache.SetByExpireCallback(key, msg, r.timeout, r.expire)
+	}
+	i := int(p.num) - 1
+	if msg.Parts[i] == nil {
+		msg.got++
+	}
+	msg.Parts[i] = pdu
+	msg.data[i] = p.data
+	if msg.got < msg.Total {
+		return nil, false
+	}
+	r.cache.Del(key)
+	msg.done = true
+	msg.join()
+	return msg, true
+}
+
+// Close 停止超时检查
+func (r *Reassembler) Close() {
+	r.stop()
+}
+
+// expire 缓存删除过期项后异步回调, 期间 Add 可能已取得该消息并收齐, 此时不再回调
+func (r *Reassembler) expire(v any) {
+	msg := v.(*LongMessage)
+	r.mu.Lock()
+	if msg.done || msg.got >= msg.Total {
+		r.mu.Unlock()
+		return
+	}
+	msg.done = true
+	msg.join()
+	r.mu.Unlock()
+	if r.OnExpire != nil {
+		r.OnExpire(msg)
+	}
+}
+
+func (m *LongMessage) join() {
+	m.Data = m.Data[:0]
+	for _, d := range m.data {
+		m.Data = append(m.Data, d...)
+	}
+	enc := m.enc
+	if enc == nil {
+		enc = codec.GSM7BIT
+	}
+	m.Text, _ = enc.Decode(m.Data)
+}
+
+type part struct {
+	src, dst   string
+	ref        uint16
+	total, num byte
+	data       []byte
+	enc        codec.Encoding
+}
+
+func parsePart(pdu PDU) (*part, bool) {
+	var (
+		p    = &part{}
+		udh  codec.UDH
+		tlvs codec.OptionalFields
+	)
+	switch m := pdu.(type) {
+	case *cmpp.DeliverReq:
+		if m.RegisterDelivery == 1 {
+			return nil, false
+		}
+		p.src, p.dst = m.SrcTerminalId, m.DestId
+		p.data, p.enc, udh = m.Message.GetMessageData(), m.Message.Encoding(), m.Message.UDHeader()
+	case *smgp.DeliverReq:
+		if m.IsReport == 1 {
+			return nil, false
+		}
+		p.src, p.dst = m.SrcTermID, m.DestTermID
+		p.data, p.enc, udh = m.Message.GetMessageData(), m.Message.Encoding(), m.Message.UDHeader()
+		tlvs = m.OptionalParameters
+		// 部分网关长短信未设置 TP_udhi
+		if udh == nil && m.Message.IsLongMessage() {
+			udh, p.data = splitUDH(p.data)
+		}
+	case *sgip.DeliverReq:
+		p.src, p.dst = m.UserNumber, m.SPNumber
+		p.data, p.enc, udh = m.Message.GetMessageData(), m.Message.Encoding(), m.Message.UDHeader()
+	case *smpp.DeliverSM:
+		if m.Report != nil {
+			return nil, false
+		}
+		p.src, p.dst = m.SourceAddr.Address(), m.DestAddr.Address()
+		p.data, p.enc, udh = m.Message.GetMessageData(), m.Message.Encoding(), m.Message.UDH()
+		tlvs = m.OptionalParameters
+		if f, ok := tlvs[codec.TagMessagePayload]; ok && len(p.data) == 0 {
+			p.data = f.Data
+			if m.EsmClass&smpp.SM_UDH_GSM > 0 {
+				udh, p.data = splitUDH(p.data)
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	if total, num, ref, ok := udh.GetConcatRef(); ok {
+		p.total, p.num, p.ref = total, num, ref
+	} else if f, ok := tlvs[codec.TagSarTotalSegments]; ok {
+		// smpp sar_* TLV
+		p.total = byte(f.UInt64())
+		if f, ok := tlvs[codec.TagSarSegmentSeqnum]; ok {
+			p.num = byte(f.UInt64())
+		}
+		if f, ok := tlvs[codec.TagSarMsgRefNum]; ok && len(f.Data) == 2 {
+			p.ref = binary.BigEndian.Uint16(f.Data)
+		}
+	} else if f, ok := tlvs[codec.TagPkTotal]; ok {
+		// smgp PkTotal/PkNumber TLV, 没有参考号
+		p.total = byte(f.UInt64())
+		if f, ok := tlvs[codec.TagPkNumber]; ok {
+			p.num = byte(f.UInt64())
+		}
+	}
+	return p, true
+}
+
+func splitUDH(data []byte) (codec.UDH, []byte) {
+	udh := codec.UDH{}
+	if _, err := udh.UnmarshalBinary(data); err != nil {
+		return nil, data
+	}
+	if n := udh.UDHL(); n > 0 && n <= len(data) {
+		return udh, data[n:]
+	}
+	return nil, data
+}
@@ -0,0 +1,150 @@
+package zysms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smpp"
+)
+
+// deliverPart smpp 上行分片, ie 为 nil 时不带 UDH
+func deliverPart(t *testing.T, text string, ie *codec.InfoElement) PDU {
+	t.Helper()
+	p := smpp.NewDeliverSM().(*smpp.DeliverSM)
+	var err error
+	p.SourceAddr, err = smpp.NewAddressWithAddr("8613800000001")
+	require.NoError(t, err)
+	p.DestAddr, err = smpp.NewAddressWithAddr("10690001")
+	require.NoError(t, err)
+	p.Message, err = smpp.NewShortMessageWithEncoding(text, codec.GSM7BIT)
+	require.NoError(t, err)
+	if ie != nil {
+		p.Message.SetUDH(codec.UDH{*ie})
+	}
+	return p
+}
+
+func concat8(total, num, ref byte) *codec.InfoElement {
+	ie := codec.NewIEConcatMessage(total, num, ref)
+	return &ie
+}
+
+func concat16(total, num byte, ref uint16) *codec.InfoElement {
+	ie := codec.NewIEConcatMessage16(total, num, ref)
+	return &ie
+}
+
+func TestReassembler(t *testing.T) {
+	r := NewReassembler(time.Minute)
+	defer r.Close()
+
+	t.Run("out of order", func(t *testing.T) {
+		_, ok := r.Add(deliverPart(t, "ghi", concat8(3, 3, 1)))
+		require.False(t, ok)
+		_, ok = r.Add(deliverPart(t, "abc", concat8(3, 1, 1)))
+		require.False(t, ok)
+		msg, ok := r.Add(deliverPart(t, "def", concat8(3, 2, 1)))
+		require.True(t, ok)
+		require.Equal(t, "abcdefghi", msg.Text)
+		require.Equal(t, 3, msg.Total)
+		require.Len(t, msg.Parts, 3)
+	})
+
+	t.Run("duplicate part", func(t *testing.T) {
+		_, ok := r.Add(deliverPart(t, "abc", concat8(2, 1, 2)))
+		require.False(t, ok)
+		// 重复的分片覆盖, 不计入数量
+		_, ok = r.Add(deliverPart(t, "xyz", concat8(2, 1, 2)))
+		require.False(t, ok)
+		msg, ok := r.Add(deliverPart(t, "def", concat8(2, 2, 2)))
+		require.True(t, ok)
+		require.Equal(t, "xyzdef", msg.Text)
+	})
+
+	t.Run("8-bit and 16-bit refs", func(t *testing.T) {
+		_, ok := r.Add(deliverPart(t, "abc", concat16(2, 1, 0x1203)))
+		require.False(t, ok)
+		// 低字节相同的 8 位参考号是另一条消息
+		_, ok = r.Add(deliverPart(t, "123", concat8(2, 1, 0x03)))
+		require.False(t, ok)
+		msg, ok := r.Add(deliverPart(t, "def", concat16(2, 2, 0x1203)))
+		require.True(t, ok)
+		require.Equal(t, uint16(0x1203), msg.Ref)
+		require.Equal(t, "abcdef", msg.Text)
+		msg, ok = r.Add(deliverPart(t, "456", concat8(2, 2, 0x03)))
+		require.True(t, ok)
+		require.Equal(t, uint16(0x03), msg.Ref)
+		require.Equal(t, "123456", msg.Text)
+	})
+
+	t.Run("invalid part numbers", func(t *testing.T) {
+		// 总数为 0 或序号超过总数时按单条处理
+		for _, ie := range []*codec.InfoElement{concat8(0, 1, 4), concat8(2, 3, 4), concat8(2, 0, 4), nil} {
+			msg, ok := r.Add(deliverPart(t, "abc", ie))
+			require.True(t, ok)
+			require.Equal(t, 1, msg.Total)
+			require.Equal(t, "abc", msg.Text)
+		}
+	})
+
+	t.Run("not a deliver", func(t *testing.T) {
+		_, ok := r.Add(smpp.NewSubmitSM())
+		require.False(t, ok)
+	})
+}
+
+func TestReassemblerExpire(t *testing.T) {
+	r := NewReassembler(20 * time.Millisecond)
+	defer r.Close()
+	expired := make(chan *LongMessage, 1)
+	r.OnExpire = func(msg *LongMessage) { expired <- msg }
+
+	_, ok := r.Add(deliverPart(t, "abc", concat8(3, 1, 5)))
+	require.False(t, ok)
+	_, ok = r.Add(deliverPart(t, "ghi", concat8(3, 3, 5)))
+	require.False(t, ok)
+	select {
+	case msg := <-expired:
+		require.Equal(t, 3, msg.Total)
+		require.NotNil(t, msg.Parts[0])
+		require.Nil(t, msg.Parts[1])
+		require.Equal(t, "abcghi", msg.Text)
+	case <-time.After(time.Second):
+		t.Fatal("not expired")
+	}
+
+	// 超时后迟到的分片重新开始缓存
+	_, ok = r.Add(deliverPart(t, "def", concat8(3, 2, 5)))
+	require.False(t, ok)
+}
+
+func TestReassemblerExpireRace(t *testing.T) {
+	r := NewReassembler(time.Minute)
+	defer r.Close()
+	expired := make(chan *LongMessage, 1)
+	r.OnExpire = func(msg *LongMessage) { expired <- msg }
+
+	_, ok := r.Add(deliverPart(t, "abc", concat8(2, 1, 7)))
+	require.False(t, ok)
+	// 缓存删除过期项后异步回调, 回调执行前 Add 已取得该消息并收齐
+	cached := r.cache.Get("8613800000001|10690001|7|2")
+	require.NotNil(t, cached)
+	msg, ok := r.Add(deliverPart(t, "def", concat8(2, 2, 7)))
+	require.True(t, ok)
+	require.Same(t, cached, msg)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.expire(cached)
+	}()
+	require.Equal(t, "abcdef", msg.Text)
+	<-done
+	select {
+	case <-expired:
+		t.Fatal("expired after completed")
+	default:
+	}
+}
@@ -0,0 +1,245 @@
+package zysms
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/utils/cache"
+)
+
+// Report 统一格式的状态报告
+type Report struct {
+	// MsgID 网关消息ID, 格式同 SendText 返回值
+	MsgID string
+	// Dest 接收号码
+	Dest string
+	// Stat 状态, DELIVRD 表示成功
+	Stat       string
+	Err        string
+	SubmitTime string
+	DoneTime   string
+	// Parts 长短信各分片的报告, 按提交顺序
+	Parts []*Report
+	PDU   PDU
+}
+
+// Delivered 是否发送成功
+func (r *Report) Delivered() bool {
+	return r.Stat == "DELIVRD"
+}
+
+// Receipt 提交记录, 用于关联状态报告
+type Receipt struct {
+	Account string
+	From    string
+	To      []string
+	Text    string
+	Time    time.Time
+	// Total 提交次数, 全部提交响应到达前不合并报告
+	Total    int
+	Segments []*Segment
+}
+
+// Segment 一次提交, 长短信每个分片一次, smpp 多号码时每个号码各一次
+type Segment struct {
+	ID      string
+	To      []string
+	Reports map[string]*Report
+}
+
+// ReceiptStore 提交记录存储, 按消息ID索引
+type ReceiptStore interface {
+	// Save 保存或更新记录, 每个分片的消息ID都指向该记录
+	Save(rec *Receipt) error
+	// Load 按消息ID查找, 不存在时返回 nil
+	Load(id string) (*Receipt, error)
+	// Delete 所有报告到达后删除
+	Delete(rec *Receipt) error
+}
+
+// MemoryReceiptStore 内存存储, 超过 ttl 未收齐报告的记录被丢弃
+type MemoryReceiptStore struct {
+	ttl   time.Duration
+	cache *cache.Memory
+	stop  func()
+}
+
+func NewMemoryReceiptStore(ttl time.Duration) *MemoryReceiptStore {
+	ctx, stop := context.WithCancel(context.Background())
+	return &MemoryReceiptStore{ttl: ttl, cache: cache.NewMemory(ctx), stop: stop}
+}
+
+func (m *MemoryReceiptStore) Save(rec *Receipt) error {
+	for _, seg := range rec.Segments {
+		// 已保存的不刷新过期时间
+		if m.cache.Get(seg.ID) != rec {
+			m.cache.SetByExpire(seg.ID, rec, m.ttl)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryReceiptStore) Load(id string) (*Receipt, error) {
+	if v := m.cache.Get(id); v != nil {
+		return v.(*Receipt), nil
+	}
+	return nil, nil
+}
+
+func (m *MemoryReceiptStore) Delete(rec *Receipt) error {
+	for _, seg := range rec.Segments {
+		m.cache.Del(seg.ID)
+	}
+	return nil
+}
+
+// Close 停止超时检查
+func (m *MemoryReceiptStore) Close() {
+	m.stop()
+}
+
+// addSegment 记录一次提交响应
+func (s *SMS) addSegment(rec *Receipt, id string, pdu PDU) error {
+	s.receiptMu.Lock()
+	defer s.receiptMu.Unlock()
+	rec.Segments = append(rec.Segments, &Segment{ID: id, To: submitTargets(pdu), Reports: map[string]*Report{}})
+	return s.Receipts.Save(rec)
+}
+
+// endReceipt 部分分片提交失败时, 只等待已提交分片的报告
+func (s *SMS) endReceipt(rec *Receipt) {
+	s.receiptMu.Lock()
+	defer s.receiptMu.Unlock()
+	rec.Total = len(rec.Segments)
+	if rec.Total > 0 {
+		s.Receipts.Save(rec)
+	}
+}
+
+// matchReport 关联状态报告, 某个号码所有分片的报告都到达后回调 OnReport
+func (s *SMS) matchReport(c *sms_conn, pdu PDU) {
+	if s.Receipts == nil {
+		return
+	}
+	rpt, ok := c.action.report(pdu)
+	if !ok {
+		return
+	}
+	s.receiptMu.Lock()
+	rec, err := s.Receipts.Load(rpt.MsgID)
+	if err != nil || rec == nil {
+		s.receiptMu.Unlock()
+		if err != nil {
+			s.doError(c, err)
+		}
+		return
+	}
+	done := rec.add(rpt)
+	if rec.complete() {
+		err = s.Receipts.Delete(rec)
+	} else {
+		err = s.Receipts.Save(rec)
+	}
+	s.receiptMu.Unlock()
+	if err != nil {
+		s.doError(c, err)
+	}
+	if done != nil && s.OnReport != nil {
+		s.OnReport(c, rec, done)
+	}
+}
+
+// add 记录分片报告, 该号码的报告收齐时返回合并后的报告
+func (r *Receipt) add(rpt *Report) *Report {
+	var seg *Segment
+	for _, v := range r.Segments {
+		if v.ID == rpt.MsgID {
+			seg = v
+			break
+		}
+	}
+	if seg == nil {
+		return nil
+	}
+	dest := matchDest(seg.To, rpt.Dest)
+	if dest == "" || seg.Reports[dest] != nil {
+		return nil
+	}
+	seg.Reports[dest] = rpt
+	if len(r.Segments) < r.Total {
+		return nil
+	}
+
+	all := &Report{Dest: dest, Stat: "DELIVRD", PDU: rpt.PDU}
+	for _, v := range r.Segments {
+		if !slices.Contains(v.To, dest) {
+			continue
+		}
+		p := v.Reports[dest]
+		if p == nil {
+			return nil
+		}
+		if all.MsgID == "" {
+			all.MsgID, all.SubmitTime = p.MsgID, p.SubmitTime
+		}
+		if p.DoneTime > all.DoneTime {
+			all.DoneTime = p.DoneTime
+		}
+		if all.Delivered() && !p.Delivered() {
+			all.Stat, all.Err = p.Stat, p.Err
+		}
+		all.Parts = append(all.Parts, p)
+	}
+	return all
+}
+
+func (r *Receipt) complete() bool {
+	if len(r.Segments) < r.Total {
+		return false
+	}
+	for _, seg := range r.Segments {
+		if len(seg.Reports) < len(seg.To) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchDest 报告中的号码可能带或不带国家码
+func matchDest(to []string, dest string) string {
+	if len(to) == 1 {
+		return to[0]
+	}
+	for _, v := range to {
+		if v == dest {
+			return v
+		}
+	}
+	for _, v := range to {
+		if dest != "" && (strings.HasSuffix(v, dest) || strings.HasSuffix(dest, v)) {
+			return v
+		}
+	}
+	return ""
+}
+
+// submitTargets 提交请求的接收号码
+func submitTargets(pdu PDU) []string {
+	switch p := pdu.(type) {
+	case *cmpp.SubmitReq:
+		return p.DestTerminalId
+	case *smgp.SubmitReq:
+		return p.DestTermID
+	case *sgip.SubmitReq:
+		return p.UserNumber
+	case *smpp.SubmitSM:
+		return []string{p.DestAddr.Address()}
+	}
+	return nil
+}
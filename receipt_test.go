@@ -0,0 +1,145 @@
+package zysms
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+)
+
+const receiptDest = "13300000001"
+
+// receiptPipe 记录提交的 cmpp 连接, 返回合并后的报告和未关联的状态报告
+func receiptPipe(t *testing.T) (*sms_conn, net.Conn, <-chan *Report, <-chan PDU) {
+	t.Helper()
+	s := New(codec.CMPP30)
+	s.Receipts = NewMemoryReceiptStore(time.Minute)
+	t.Cleanup(s.Receipts.(*MemoryReceiptStore).Close)
+	reports := make(chan *Report, 4)
+	s.OnReport = func(c Conn, rec *Receipt, rpt *Report) {
+		reports <- rpt
+	}
+	recvs := make(chan PDU, 4)
+	s.OnRecv = func(c Conn, p PDU) {
+		recvs <- p
+	}
+	c, peer := testPipeOf(t, s)
+	return c, peer, reports, recvs
+}
+
+// sendLong 后台提交两个分片的长短信, 返回对端收到的提交请求和 SendText 的结果
+func sendLong(t *testing.T, c *sms_conn, peer net.Conn) ([]*cmpp.SubmitReq, <-chan []string) {
+	t.Helper()
+	submits := readPDUs(peer, 2)
+	ids := make(chan []string, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		v, err := c.SendText(ctx, Message{From: "10690001", To: []string{receiptDest}, Text: strings.Repeat("测", 100), RequestReport: true})
+		if err != nil {
+			t.Error(err)
+		}
+		ids <- v
+	}()
+	var reqs []*cmpp.SubmitReq
+	for p := range submits {
+		reqs = append(reqs, p.(*cmpp.SubmitReq))
+	}
+	require.Len(t, reqs, 2)
+	return reqs, ids
+}
+
+func submitResp(t *testing.T, peer net.Conn, req *cmpp.SubmitReq, id uint64) {
+	t.Helper()
+	resp := req.GetResponse().(*cmpp.SubmitResp)
+	resp.MsgId = id
+	writePDU(t, peer, resp)
+}
+
+func deliverReport(t *testing.T, peer net.Conn, id uint64, stat string) {
+	t.Helper()
+	p := cmpp.NewDeliverReq(cmpp.V30).(*cmpp.DeliverReq)
+	p.DestId = "10690001"
+	p.SrcTerminalId = receiptDest
+	p.Report = &cmpp.DeliverReport{MsgId: id, Stat: stat, DestTerminalId: receiptDest}
+	writePDU(t, peer, p)
+}
+
+func requireNoReport(t *testing.T, reports <-chan *Report) {
+	t.Helper()
+	select {
+	case r := <-reports:
+		t.Fatalf("unexpected report %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestReceiptAllDelivered(t *testing.T) {
+	c, peer, reports, _ := receiptPipe(t)
+	reqs, ids := sendLong(t, c, peer)
+	submitResp(t, peer, reqs[0], 1)
+	submitResp(t, peer, reqs[1], 2)
+	require.Equal(t, []string{"1", "2"}, <-ids)
+
+	deliverReport(t, peer, 1, "DELIVRD")
+	requireNoReport(t, reports)
+	deliverReport(t, peer, 2, "DELIVRD")
+	rpt := <-reports
+	require.True(t, rpt.Delivered())
+	require.Equal(t, "1", rpt.MsgID)
+	require.Equal(t, receiptDest, rpt.Dest)
+	require.Len(t, rpt.Parts, 2)
+	require.Equal(t, "2", rpt.Parts[1].MsgID)
+}
+
+func TestReceiptPartFailed(t *testing.T) {
+	c, peer, reports, _ := receiptPipe(t)
+	reqs, ids := sendLong(t, c, peer)
+	submitResp(t, peer, reqs[0], 1)
+	submitResp(t, peer, reqs[1], 2)
+	<-ids
+
+	deliverReport(t, peer, 2, "UNDELIV")
+	requireNoReport(t, reports)
+	deliverReport(t, peer, 1, "DELIVRD")
+	rpt := <-reports
+	require.False(t, rpt.Delivered())
+	require.Equal(t, "UNDELIV", rpt.Stat)
+	require.Len(t, rpt.Parts, 2)
+	// 分片按提交顺序
+	require.Equal(t, "1", rpt.Parts[0].MsgID)
+}
+
+func TestReceiptReportBeforeResp(t *testing.T) {
+	c, peer, reports, _ := receiptPipe(t)
+	reqs, ids := sendLong(t, c, peer)
+	// 第一个分片的报告先于第二个分片的提交响应到达
+	submitResp(t, peer, reqs[0], 1)
+	deliverReport(t, peer, 1, "DELIVRD")
+	requireNoReport(t, reports)
+	submitResp(t, peer, reqs[1], 2)
+	require.Equal(t, []string{"1", "2"}, <-ids)
+	deliverReport(t, peer, 2, "DELIVRD")
+	rpt := <-reports
+	require.True(t, rpt.Delivered())
+	require.Len(t, rpt.Parts, 2)
+}
+
+func TestReceiptUnknownMsgId(t *testing.T) {
+	c, peer, reports, recvs := receiptPipe(t)
+	reqs, ids := sendLong(t, c, peer)
+	submitResp(t, peer, reqs[0], 1)
+	submitResp(t, peer, reqs[1], 2)
+	<-ids
+
+	// 未知消息ID的报告只交给 OnRecv
+	deliverReport(t, peer, 99, "DELIVRD")
+	p := <-recvs
+	require.Equal(t, uint64(99), p.(*cmpp.DeliverReq).Report.MsgId)
+	requireNoReport(t, reports)
+}
@@ -0,0 +1,253 @@
+package zysms
+
+import (
+	"net"
+	"sync"
+
+	"github.com/zhiyin2021/zysms/enum"
+)
+
+// Registry 存活的连接, 按 SID、登录账号及对端 IP 索引, 关闭后不再加入新连接
+type Registry struct {
+	mu       sync.Mutex
+	items    map[*sms_conn]struct{}
+	sids     map[string]*sms_conn
+	ips      map[string]map[*sms_conn]struct{}
+	accounts map[string]map[*sms_conn]struct{}
+	// logins 已登录连接的账号
+	logins map[*sms_conn]string
+	closed bool
+
+	// 登录连接数限制, 0 不限制
+	maxConns      int
+	maxPerAccount int
+	maxPerIP      int
+}
+
+// setLimit 设置登录时校验的最大连接数: 总数、每个账号、每个 IP, 0 不限制
+func (r *Registry) setLimit(total, perAccount, perIP int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxConns, r.maxPerAccount, r.maxPerIP = total, perAccount, perIP
+}
+
+// Get 按 SID 查找连接
+func (r *Registry) Get(sid string) Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.sids[sid]; ok {
+		return c
+	}
+	return nil
+}
+
+// All 所有连接, 包括未登录的
+func (r *Registry) All() []Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return toConns(r.items)
+}
+
+// Len 连接数, 包括未登录的
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.items)
+}
+
+// Account 账号已登录的连接
+func (r *Registry) Account(account string) []Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return toConns(r.accounts[account])
+}
+
+// IP 对端 IP 的连接
+func (r *Registry) IP(ip string) []Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return toConns(r.ips[ip])
+}
+
+// Bound 账号下当前状态允许发送 pdu 的已登录连接, 如 smpp 推送 deliver_sm 时排除 tx 方式登录的连接, 没有时返回 nil
+func (r *Registry) Bound(account string, pdu PDU) Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for c := range r.accounts[account] {
+		switch c.BindState() {
+		case enum.BIND_TX, enum.BIND_RX, enum.BIND_TRX:
+			if c.IsConnected() && c.checkBindState(pdu) == nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+func toConns(m map[*sms_conn]struct{}) []Conn {
+	conns := make([]Conn, 0, len(m))
+	for c := range m {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// add 加入连接, limit 为 true 时按总数及每个 IP 的连接数(包括未登录的)限制, 超过时返回 false
+func (r *Registry) add(c *sms_conn, limit bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+	if limit && (r.maxConns > 0 && len(r.items) >= r.maxConns ||
+		r.maxPerIP > 0 && len(r.ips[c.remoteIP()]) >= r.maxPerIP) {
+		return false
+	}
+	if r.items == nil {
+		r.items = map[*sms_conn]struct{}{}
+		r.sids = map[string]*sms_conn{}
+		r.ips = map[string]map[*sms_conn]struct{}{}
+		r.accounts = map[string]map[*sms_conn]struct{}{}
+		r.logins = map[*sms_conn]string{}
+	}
+	r.items[c] = struct{}{}
+	r.sids[c.sid] = c
+	index(r.ips, c.remoteIP(), c)
+	// 客户端连接在加入前已登录
+	if c.account != "" {
+		r.logins[c] = c.account
+		index(r.accounts, c.account, c)
+	}
+	return true
+}
+
+// login 服务端登录成功时登记账号, 超过连接数限制时返回 false
+func (r *Registry) login(c *sms_conn, account string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[c]; !ok {
+		return true
+	}
+	if r.maxConns > 0 && len(r.logins) >= r.maxConns {
+		return false
+	}
+	if r.maxPerAccount > 0 && len(r.accounts[account]) >= r.maxPerAccount {
+		return false
+	}
+	if r.maxPerIP > 0 {
+		n := 0
+		for o := range r.ips[c.remoteIP()] {
+			if _, ok := r.logins[o]; ok {
+				n++
+			}
+		}
+		if n >= r.maxPerIP {
+			return false
+		}
+	}
+	r.logins[c] = account
+	index(r.accounts, account, c)
+	return true
+}
+
+// logout 取消登记的账号
+func (r *Registry) logout(c *sms_conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if account, ok := r.logins[c]; ok {
+		delete(r.logins, c)
+		unindex(r.accounts, account, c)
+	}
+}
+
+func (r *Registry) remove(c *sms_conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[c]; !ok {
+		return
+	}
+	delete(r.items, c)
+	delete(r.sids, c.sid)
+	unindex(r.ips, c.remoteIP(), c)
+	if account, ok := r.logins[c]; ok {
+		delete(r.logins, c)
+		unindex(r.accounts, account, c)
+	}
+}
+
+func (r *Registry) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// close 标记关闭, 返回当前的连接
+func (r *Registry) close() []*sms_conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	conns := make([]*sms_conn, 0, len(r.items))
+	for c := range r.items {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+func index(m map[string]map[*sms_conn]struct{}, key string, c *sms_conn) {
+	if m[key] == nil {
+		m[key] = map[*sms_conn]struct{}{}
+	}
+	m[key][c] = struct{}{}
+}
+
+func unindex(m map[string]map[*sms_conn]struct{}, key string, c *sms_conn) {
+	if set, ok := m[key]; ok {
+		delete(set, c)
+		if len(set) == 0 {
+			delete(m, key)
+		}
+	}
+}
+
+// remoteIP 对端 IP, 用于按 IP 索引及限制连接数
+func (c *sms_conn) remoteIP() string {
+	addr := c.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// register 服务端登录成功后登记账号, 超过 SMS 或所属监听的连接数限制时返回 false
+func (c *sms_conn) register(account string) bool {
+	if !c.parent.conns.login(c, account) {
+		return false
+	}
+	if l := c.listener; l != nil && !l.conns.login(c, account) {
+		c.parent.conns.logout(c)
+		return false
+	}
+	return true
+}
+
+// Conns 连接登记, 可按 SID、账号及 IP 查找
+func (s *SMS) Conns() *Registry {
+	return &s.conns
+}
+
+// SetConnLimit 设置登录时校验的最大连接数: 总数、每个账号、每个 IP, 0 不限制.
+// 超过时按协议回复: cmpp 5 其他错误, smgp 2 超过最大连接数, sgip 3 连接过多, smpp ESME_RBINDFAIL.
+// 未设置 Authenticator 时库不校验登录, 总数及每个 IP 的限制在接入时按所有连接计算, 超过时直接断开, 每个账号的限制不生效
+func (s *SMS) SetConnLimit(total, perAccount, perIP int) {
+	s.conns.setLimit(total, perAccount, perIP)
+}
+
+// Conns 该监听上的连接
+func (l *Listener) Conns() *Registry {
+	return &l.conns
+}
+
+// SetConnLimit 该监听的连接数限制, 与 SMS.SetConnLimit 同时生效
+func (l *Listener) SetConnLimit(total, perAccount, perIP int) {
+	l.conns.setLimit(total, perAccount, perIP)
+}
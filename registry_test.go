@@ -0,0 +1,64 @@
+package zysms
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+func TestConnLimitWithoutAuthenticator(t *testing.T) {
+	tests := []struct {
+		name         string
+		total, perIP int
+	}{
+		{"total", 1, 0},
+		{"per ip", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(codec.CMPP30)
+			s.SetConnLimit(tt.total, 0, tt.perIP)
+			l, err := s.Listen("127.0.0.1:0")
+			require.NoError(t, err)
+			t.Cleanup(func() { shutdownNow(s) })
+
+			c1, err := net.Dial("tcp", l.Addr().String())
+			require.NoError(t, err)
+			defer c1.Close()
+			require.Eventually(t, func() bool { return l.Conns().Len() == 1 }, time.Second, 5*time.Millisecond)
+
+			// 未设置 Authenticator 时接入即按连接数限制, 超过的连接直接断开
+			c2, err := net.Dial("tcp", l.Addr().String())
+			require.NoError(t, err)
+			defer c2.Close()
+			c2.SetReadDeadline(time.Now().Add(time.Second))
+			_, err = c2.Read(make([]byte, 1))
+			require.ErrorIs(t, err, io.EOF)
+			require.Equal(t, 1, l.Conns().Len())
+		})
+	}
+}
+
+func TestSmgpLoginStatus(t *testing.T) {
+	s, l := testListen(t, codec.SMGP30)
+	s.SetConnLimit(0, 1, 0)
+	_, c := testDial(t, codec.SMGP30, l, nil)
+
+	// 超过连接数回复 2, 重复登录回复 21
+	_, err := New(codec.SMGP30).Dial(l.Addr().String(), testUid, testPwd, time.Second, nil)
+	var e *smserror.SmsError
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, 2, e.Code)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := c.Request(ctx, smgp.NewLoginReq(smgp.V30))
+	require.NoError(t, err)
+	require.Equal(t, smgp.Status(21), resp.(*smgp.LoginResp).Status)
+}
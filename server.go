@@ -1,15 +1,23 @@
 package zysms
 
 import (
-	"crypto/rand"
+	"bufio"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"runtime/debug"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
 	"github.com/zhiyin2021/zysms/utils/logger"
 	"go.uber.org/zap"
 )
@@ -25,7 +33,34 @@ type (
 		OnRecv       func(Conn, PDU)
 		// 心跳未响应次数
 		OnHeartbeatNoResp func(Conn, int)
-		extParam          map[string]string
+		// Authenticator 设置后由库校验登录请求
+		Authenticator Authenticator
+		// ACL 服务端按对端 IP 的访问控制, Listener.ACL 优先, 不允许的连接回复登录失败后断开
+		ACL *ACL
+		// ProxyProtocol 监听的连接以 PROXY protocol v1/v2 头开始, 使用头中的客户端地址, 只接受 TrustedProxies 中的代理, ListenTls 时返回错误
+		ProxyProtocol bool
+		// TrustedProxies 允许发送 PROXY 头的代理地址, 其他地址的连接直接断开, 为空时不接受任何连接
+		TrustedProxies *ACL
+		// TLSConfig Dial 的 TLS 配置, 不为空或 ext tls=1 时使用 TLS, 为空时使用默认配置并校验服务端证书
+		TLSConfig *tls.Config
+		// CertAccount 双向认证时由客户端证书得到账号, 须与登录账号一致, 默认为证书的 CommonName
+		CertAccount func(cert *x509.Certificate) string
+		// NodeId sgip 节点编号, 填入序列号第一部分, 连接可用 node_id 覆盖
+		NodeId uint32
+		// Receipts 设置后 SendText 记录提交, 状态报告到达时关联并回调 OnReport
+		Receipts ReceiptStore
+		// OnReport 某个号码的所有分片报告到达时回调, report.Parts 为各分片报告
+		OnReport func(c Conn, original *Receipt, report *Report)
+		// OnCancel cmpp 收到删除短信请求, msgId 格式同 SendText 返回值, 返回是否删除成功
+		OnCancel  func(c Conn, msgId string) bool
+		receiptMu sync.Mutex
+		// 存活的连接及监听, 见 Shutdown
+		conns      Registry
+		listeners  []*Listener
+		listenerMu sync.Mutex
+		extParam   map[string]string
+		// 账号限速,见 SetRateLimiter
+		limiters sync.Map
 	}
 
 	Conn interface {
@@ -36,6 +71,16 @@ type (
 		// Recv() ([]byte, error)
 		// RecvPDU() (codec.PDU, error)
 		SendPDU(PDU) error
+		// SendAsync 发送请求,返回等待响应的 Future
+		SendAsync(PDU) (*Future, error)
+		// Request 发送请求并等待响应
+		Request(context.Context, PDU) (PDU, error)
+		// InFlight 已发送未收到响应的请求数
+		InFlight() int
+		// SendText 拆分并提交短信, 返回网关消息ID
+		SendText(context.Context, Message) ([]string, error)
+		// Cancel 删除已提交未下发的短信, 仅支持 cmpp
+		Cancel(ctx context.Context, msgId string) error
 		Logger() *zap.SugaredLogger
 		Ver() codec.Version
 		sendActiveTest() (int32, error)
@@ -44,6 +89,14 @@ type (
 		GetData() any
 		SetData(any)
 		SID() string
+		// Proto 连接使用的协议
+		Proto() codec.SmsProto
+		// Account 登录账号
+		Account() string
+		// PeerCertificate 对端已校验的 TLS 证书
+		PeerCertificate() *x509.Certificate
+		// BindState 登录状态 enum.BIND_*
+		BindState() int32
 		Delay() []int64
 		IsConnected() bool
 		EnabledActiveTest()
@@ -54,6 +107,7 @@ func New(proto codec.SmsProto) *SMS {
 	return &SMS{proto: proto, extParam: map[string]string{}}
 }
 
+// Listen 协议为 codec.AUTO 时按登录报文识别每个连接的协议
 func (s *SMS) Listen(addr string) (*Listener, error) {
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -63,35 +117,16 @@ func (s *SMS) Listen(addr string) (*Listener, error) {
 	if err != nil {
 		return nil, err
 	}
-	tryGO(func() {
-		for {
-			sConn, err := l.accept()
-			if err != nil {
-				logger.Errorln("listen.accept error:", err)
-				if e, ok := err.(*net.OpError); ok && e.Error() == "use of closed network connection" {
-					return
-				}
-				continue
-			}
-			s.run(sConn)
-
-		}
-	})
+	tryGO(l.serve)
 	return l, nil
 }
-func (s *SMS) ListenTls(addr string, cert []byte, key []byte) (*Listener, error) {
-	crt, err := tls.X509KeyPair(cert, key)
-	if err != nil {
-		logger.Errorln(err.Error())
-		return nil, err
+
+// ListenOutbind smpp 客户端监听服务端的 outbind, 收到后以 uid/pwd 发起 bind_receiver
+func (s *SMS) ListenOutbind(addr string, uid, pwd string) (*Listener, error) {
+	if s.proto.Raw() != "smpp" {
+		return nil, smserror.ErrProtoNotSupport
 	}
-	tlsConfig := &tls.Config{InsecureSkipVerify: true}
-	tlsConfig.Certificates = []tls.Certificate{crt}
-	// Time returns the current time as the number of seconds since the epoch.
-	// If Time is nil, TLS uses time.Now.
-	tlsConfig.Time = time.Now
-	tlsConfig.Rand = rand.Reader
-	ln, err := tls.Listen("tcp", addr, tlsConfig)
+	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
@@ -99,21 +134,20 @@ func (s *SMS) ListenTls(addr string, cert []byte, key []byte) (*Listener, error)
 	if err != nil {
 		return nil, err
 	}
-	tryGO(func() {
-		for {
-			sConn, err := l.accept()
-			if err != nil {
-				logger.Errorf("listen.accept error:%s", err)
-				if e, ok := err.(*net.OpError); ok && e.Error() == "use of closed network connection" {
-					return
-				}
-				continue
-			}
-			s.run(sConn)
-		}
-	})
+	l.outbind = &Account{ID: uid, Password: pwd}
+	tryGO(l.serve)
 	return l, nil
 }
+
+// ListenTls 使用 PEM 格式的证书及私钥监听 TLS, 更多配置见 ListenTlsConfig
+func (s *SMS) ListenTls(addr string, cert []byte, key []byte) (*Listener, error) {
+	crt, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		logger.Errorln(err.Error())
+		return nil, err
+	}
+	return s.ListenTlsConfig(addr, &tls.Config{Certificates: []tls.Certificate{crt}})
+}
 func (s *SMS) doError(conn Conn, err error) {
 	if s.OnError != nil {
 		if !strings.Contains(err.Error(), "use of closed network connection") {
@@ -127,10 +161,13 @@ func (s *SMS) doDisconnect(conn Conn) {
 	}
 }
 func (s *SMS) Dial(addr string, uid, pwd string, timeout time.Duration, ext map[string]string) (Conn, error) {
+	if s.conns.isClosed() {
+		return nil, smserror.ErrServerClosed
+	}
 	var err error
 	var conn net.Conn
-	if ext["tls"] == "1" {
-		conn, err = tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if ext["tls"] == "1" || s.TLSConfig != nil {
+		conn, err = s.dialTLS(addr, timeout)
 		if err != nil {
 			return nil, err
 		}
@@ -144,13 +181,14 @@ func (s *SMS) Dial(addr string, uid, pwd string, timeout time.Duration, ext map[
 		tc.SetKeepAlivePeriod(30 * time.Second) // 1min
 	}
 
-	sConn := newConn(conn, s)
+	sConn := newConn(conn, s, s.proto)
 	if sConn == nil {
 		return nil, fmt.Errorf("不支持的协议版本")
 	}
 	sConn.SetExtParam(ext)
 	err = sConn.Auth(uid, pwd)
 	if err != nil {
+		sConn.Close()
 		return nil, err
 	}
 	// sConn.startActiveTest(s.doError, s.OnHeartbeatNoResp)
@@ -158,24 +196,114 @@ func (s *SMS) Dial(addr string, uid, pwd string, timeout time.Duration, ext map[
 	return sConn, nil
 }
 
+// DialPair smpp 分别以 tx、rx 方式建立两条连接, 其余参数同 Dial
+func (s *SMS) DialPair(addr string, uid, pwd string, timeout time.Duration, ext map[string]string) (tx Conn, rx Conn, err error) {
+	if s.proto.Raw() != "smpp" {
+		return nil, nil, smserror.ErrProtoNotSupport
+	}
+	bind := func(typ string) map[string]string {
+		m := maps.Clone(ext)
+		if m == nil {
+			m = map[string]string{}
+		}
+		m["bind_type"] = typ
+		return m
+	}
+	if tx, err = s.Dial(addr, uid, pwd, timeout, bind("tx")); err != nil {
+		return nil, nil, err
+	}
+	if rx, err = s.Dial(addr, uid, pwd, timeout, bind("rx")); err != nil {
+		tx.Close()
+		return nil, nil, err
+	}
+	return tx, rx, nil
+}
+
+// Outbind smpp 服务端连接客户端并发送 outbind, 客户端随后发起的 bind_receiver 由 Authenticator 校验
+func (s *SMS) Outbind(addr string, uid, pwd string, timeout time.Duration, ext map[string]string) (Conn, error) {
+	if s.proto.Raw() != "smpp" {
+		return nil, smserror.ErrProtoNotSupport
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	sConn := newConn(conn, s, s.proto)
+	sConn.SetExtParam(ext)
+	p := smpp.NewOutbind().(*smpp.Outbind)
+	p.SystemID = uid
+	p.Password = pwd
+	if err := sConn.SendPDU(p); err != nil {
+		sConn.Close()
+		return nil, err
+	}
+	s.run(sConn)
+	return sConn, nil
+}
+
 func (s *SMS) run(conn *sms_conn) {
+	// 已关闭的不再接受新连接
+	l := conn.listener
+	// 未设置 Authenticator 的服务端连接不经过登录校验, 接入时限制连接数
+	limit := l != nil && conn.auth() == nil
+	if !s.conns.add(conn, limit) {
+		conn.logger.Warnln("connection rejected: closed or limit exceeded")
+		conn.close()
+		return
+	}
+	if l != nil && !l.conns.add(conn, limit) {
+		conn.logger.Warnln("connection rejected: closed or limit exceeded")
+		s.conns.remove(conn)
+		conn.close()
+		return
+	}
 	tryGO(func() {
+		defer func() {
+			s.conns.remove(conn)
+			if l != nil {
+				l.conns.remove(conn)
+			}
+		}()
+		atomic.StoreInt32(&conn.dispatching, 1)
 		if s.OnConnect != nil {
 			s.OnConnect(conn)
 		}
 		defer func() {
 			s.doDisconnect(conn)
-			conn.Close()
+			// 接收协程已退出, 无法等待退出响应
+			conn.close()
 		}()
 		// 设置第一次读取超时10秒,10秒内没有数据则主动断开请求.
 		conn.SetReadDeadline(time.Second * 10)
 		for {
+			atomic.StoreInt32(&conn.dispatching, 0)
 			pkt, err := conn.action.recv()
+			atomic.StoreInt32(&conn.dispatching, 1)
 			if err != nil {
 				s.doError(conn, err)
 				return
 			}
 			conn.Conn.SetReadDeadline(time.Time{})
+			if conn.resolve(pkt) {
+				continue
+			}
+			if ok, err := conn.rejectByState(pkt); err != nil {
+				s.doError(conn, err)
+				return
+			} else if ok {
+				continue
+			}
+			if ok, err := conn.handleLogin(pkt); err != nil {
+				s.doError(conn, err)
+				return
+			} else if ok {
+				continue
+			}
+			if conn.handleCancel(pkt) {
+				continue
+			}
+			// 状态报告关联后仍交给 OnRecv, 由调用方响应
+			s.matchReport(conn, pkt)
 			if s.OnRecv != nil {
 				// p := &Packet{conn, pkt, nil}
 				s.OnRecv(conn, pkt)
@@ -194,20 +322,60 @@ func (s *SMS) run(conn *sms_conn) {
 type Listener struct {
 	net.Listener
 	parent *SMS
+	// Authenticator 不为空时替代 SMS.Authenticator
+	Authenticator Authenticator
+	// ACL 不为空时替代 SMS.ACL
+	ACL       *ACL
+	outbind   *Account
+	loginType byte
+	conns     Registry
+	// tlsConfig ListenTlsConfig 当前使用的配置
+	tlsConfig *atomic.Pointer[tls.Config]
 	// extParam map[string]string
 	// proto    codec.SmsProto
 }
 
 func newListener(l net.Listener, parent *SMS) (*Listener, error) {
 	switch parent.proto {
-	case codec.CMPP20, codec.CMPP21, codec.CMPP30, codec.SMGP30, codec.SGIP, codec.SMPP33, codec.SMPP34:
+	case codec.CMPP20, codec.CMPP21, codec.CMPP30, codec.SMGP30, codec.SGIP, codec.SMPP33, codec.SMPP34, codec.SMPP50, codec.AUTO:
 	default:
 		return nil, fmt.Errorf("不支持的协议版本")
 	}
-	return &Listener{l, parent}, nil
+	ln := &Listener{Listener: l, parent: parent}
+	parent.listenerMu.Lock()
+	parent.listeners = append(parent.listeners, ln)
+	parent.listenerMu.Unlock()
+	return ln, nil
+}
+
+func (l *Listener) serve() {
+	for {
+		c, err := l.accept()
+		if err != nil {
+			logger.Errorln("listen.accept error:", err)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		// 识别协议需要读取数据, 不阻塞 accept
+		tryGO(func() {
+			sConn, err := l.newConn(c)
+			if err != nil {
+				logger.Errorln("listen.conn error:", err)
+				c.Close()
+				return
+			}
+			if !l.acl().Allowed(sConn.RemoteAddr()) {
+				sConn.deny()
+				return
+			}
+			l.parent.run(sConn)
+		})
+	}
 }
 
-func (l *Listener) accept() (*sms_conn, error) {
+func (l *Listener) accept() (net.Conn, error) {
 	c, err := l.Listener.Accept()
 	if err != nil {
 		return nil, err
@@ -220,14 +388,53 @@ func (l *Listener) accept() (*sms_conn, error) {
 		cc.NetConn().(*net.TCPConn).SetKeepAlive(true)
 		cc.NetConn().(*net.TCPConn).SetKeepAlivePeriod(30 * time.Second)
 	}
+	return c, nil
+}
 
-	conn := newConn(c, l.parent)
+func (l *Listener) newConn(c net.Conn) (*sms_conn, error) {
+	proto := l.parent.proto
+	if l.parent.ProxyProtocol {
+		if l.tlsConfig != nil {
+			return nil, errProxyTls
+		}
+		// 先按实际地址检查代理, 不信任的地址不读取 PROXY 头
+		if ts := l.parent.TrustedProxies; ts == nil || !ts.Allowed(c.RemoteAddr()) {
+			return nil, fmt.Errorf("%w: %s 不是可信的代理", smserror.ErrAddrNotAllowed, c.RemoteAddr())
+		}
+		c.SetReadDeadline(time.Now().Add(10 * time.Second))
+		r := bufio.NewReader(c)
+		addr, err := readProxyHeader(r)
+		if err != nil {
+			return nil, err
+		}
+		c = &proxyConn{peekConn: peekConn{Conn: c, r: r}, remote: addr}
+	}
+	if proto == codec.AUTO {
+		// 与首次读取超时一致, 10秒内未收到登录报文则断开
+		c.SetReadDeadline(time.Now().Add(10 * time.Second))
+		r := bufio.NewReader(c)
+		var err error
+		if proto, err = detectProto(r); err != nil {
+			return nil, err
+		}
+		c = &peekConn{Conn: c, r: r}
+	}
+	conn := newConn(c, l.parent, proto)
 	if conn == nil {
 		return nil, fmt.Errorf("不支持的协议版本")
 	}
+	conn.authenticator = l.Authenticator
+	conn.outbind = l.outbind
+	conn.loginType = l.loginType
+	if l.loginType != 0 {
+		// SgipEndpoint 的反向连接不发送心跳, 避免 SMG 把空的 ReportReq 当作状态报告
+		conn.activeInterval = 0
+	}
+	conn.listener = l
 	return conn, nil
 }
 
+// Close 只关闭监听, 已建立的连接不受影响, 见 Shutdown
 func (l *Listener) Close() error {
 	return l.Listener.Close()
 }
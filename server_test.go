@@ -0,0 +1,117 @@
+package zysms
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/enum"
+	"go.uber.org/zap"
+)
+
+const (
+	testUid = "900001"
+	testPwd = "123456"
+)
+
+// testAuth 只接受 testUid/testPwd
+var testAuth = AuthenticatorFunc(func(conn Conn, id string) (*Account, error) {
+	if id != testUid {
+		return nil, nil
+	}
+	return &Account{ID: testUid, Password: testPwd}, nil
+})
+
+// testListen 监听本地随机端口, 由库校验登录, 测试结束时关闭
+func testListen(t *testing.T, proto codec.SmsProto) (*SMS, *Listener) {
+	t.Helper()
+	s := New(proto)
+	s.Authenticator = testAuth
+	l, err := s.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownNow(s) })
+	return s, l
+}
+
+// testDial 登录 l, 测试结束时关闭
+func testDial(t *testing.T, proto codec.SmsProto, l *Listener, ext map[string]string) (*SMS, *sms_conn) {
+	t.Helper()
+	s := New(proto)
+	conn, err := s.Dial(l.Addr().String(), testUid, testPwd, time.Second, ext)
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownNow(s) })
+	return s, conn.(*sms_conn)
+}
+
+func shutdownNow(s *SMS) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	s.Shutdown(ctx)
+}
+
+// serverConn 等待 l 上登录成功的连接
+func serverConn(t *testing.T, l *Listener) *sms_conn {
+	t.Helper()
+	var conn *sms_conn
+	require.Eventually(t, func() bool {
+		for _, c := range l.Conns().All() {
+			switch c.BindState() {
+			case enum.BIND_TX, enum.BIND_RX, enum.BIND_TRX:
+				conn = c.(*sms_conn)
+				return true
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+	return conn
+}
+
+// testPipe 已登录的连接及 net.Pipe 的对端, 连接由 run 接收
+func testPipe(t *testing.T, proto codec.SmsProto) (*sms_conn, net.Conn) {
+	t.Helper()
+	return testPipeOf(t, New(proto))
+}
+
+// testPipeOf 同 testPipe, 连接属于 s
+func testPipeOf(t *testing.T, s *SMS) (*sms_conn, net.Conn) {
+	t.Helper()
+	local, peer := net.Pipe()
+	c := newConn(local, s, s.proto)
+	c.IsAuth = true
+	c.setBindState(enum.BIND_TRX)
+	s.run(c)
+	t.Cleanup(func() {
+		c.close()
+		peer.Close()
+	})
+	return c, peer
+}
+
+func writePDU(t *testing.T, w io.Writer, p codec.PDU) {
+	t.Helper()
+	wr := codec.NewWriter()
+	p.Marshal(wr)
+	_, err := w.Write(wr.Bytes())
+	require.NoError(t, err)
+}
+
+// readPDUs 在后台读取对端收到的 n 个 cmpp 报文
+func readPDUs(peer net.Conn, n int) <-chan codec.PDU {
+	ch := make(chan codec.PDU, n)
+	go func() {
+		defer close(ch)
+		for i := 0; i < n; i++ {
+			p, err := cmpp.Parse(peer, cmpp.V30, zap.NewNop().Sugar())
+			if err != nil {
+				return
+			}
+			ch <- p
+		}
+	}()
+	return ch
+}
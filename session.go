@@ -0,0 +1,256 @@
+package zysms
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhiyin2021/zysms/enum"
+	"github.com/zhiyin2021/zysms/smserror"
+	"github.com/zhiyin2021/zysms/utils"
+)
+
+// Session 自动重连的客户端会话,断开后按指数退避重新 Dial 并登录
+type Session struct {
+	// OnStateChange 状态变化通知, err 为进入退避前的连接错误
+	OnStateChange func(*Session, int32, error)
+
+	sms     *SMS
+	addr    string
+	uid     string
+	pwd     string
+	timeout time.Duration
+	ext     map[string]string
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	queueSize  int
+
+	state int32
+	mu    sync.Mutex
+	conn  *sms_conn
+	ready chan struct{}
+	queue []PDU
+
+	ctx     context.Context
+	stop    func()
+	started int32
+	done    chan struct{}
+}
+
+/*
+reconnect_min 首次重连等待(秒)
+reconnect_max 最大重连等待(秒)
+queue_size 断开期间发送排队数量,0 表示直接拒绝
+其余参数同 Conn.SetExtParam
+*/
+func (s *SMS) NewSession(addr string, uid, pwd string, timeout time.Duration, ext map[string]string) *Session {
+	ctx, stop := context.WithCancel(context.Background())
+	return &Session{
+		sms:        s,
+		addr:       addr,
+		uid:        uid,
+		pwd:        pwd,
+		timeout:    timeout,
+		ext:        ext,
+		minBackoff: time.Duration(utils.MapItem(ext, "reconnect_min", 1)) * time.Second,
+		maxBackoff: time.Duration(utils.MapItem(ext, "reconnect_max", 60)) * time.Second,
+		queueSize:  utils.MapItem(ext, "queue_size", 0),
+		state:      enum.SESSION_CONNECTING,
+		ready:      make(chan struct{}),
+		ctx:        ctx,
+		stop:       stop,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start 开始连接,连接及重连在后台进行
+func (s *Session) Start() {
+	if atomic.CompareAndSwapInt32(&s.started, 0, 1) {
+		tryGO(s.loop)
+	}
+}
+
+// State 当前状态 enum.SESSION_*
+func (s *Session) State() int32 {
+	return atomic.LoadInt32(&s.state)
+}
+
+// Conn 当前连接,未登录时返回 nil
+func (s *Session) Conn() Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn
+}
+
+// SendPDU 已登录时直接发送,否则按 queue_size 排队或拒绝
+func (s *Session) SendPDU(pdu PDU) error {
+	if pdu == nil {
+		return smserror.ErrPktIsNil
+	}
+	s.mu.Lock()
+	if s.State() == enum.SESSION_CLOSED {
+		s.mu.Unlock()
+		return smserror.ErrSessionClosed
+	}
+	if c := s.conn; c != nil {
+		s.mu.Unlock()
+		return c.SendPDU(pdu)
+	}
+	defer s.mu.Unlock()
+	if s.queueSize <= 0 {
+		return smserror.ErrSessionDown
+	}
+	if len(s.queue) >= s.queueSize {
+		return smserror.ErrSessionQueueFull
+	}
+	s.queue = append(s.queue, pdu)
+	return nil
+}
+
+// Request 发送请求并等待响应,断开期间开启排队时等待重新登录
+func (s *Session) Request(ctx context.Context, pdu PDU) (PDU, error) {
+	for {
+		s.mu.Lock()
+		c, ready := s.conn, s.ready
+		s.mu.Unlock()
+		if s.State() == enum.SESSION_CLOSED {
+			return nil, smserror.ErrSessionClosed
+		}
+		if c != nil {
+			return c.Request(ctx, pdu)
+		}
+		if s.queueSize <= 0 {
+			return nil, smserror.ErrSessionDown
+		}
+		select {
+		case <-ready:
+		case <-s.ctx.Done():
+			return nil, smserror.ErrSessionClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close 关闭会话及当前连接,排队中的报文被丢弃
+func (s *Session) Close() {
+	s.stop()
+	if atomic.CompareAndSwapInt32(&s.started, 0, 1) {
+		atomic.StoreInt32(&s.state, enum.SESSION_CLOSED)
+		close(s.done)
+	}
+	<-s.done
+}
+
+func (s *Session) setState(state int32, err error) {
+	if atomic.SwapInt32(&s.state, state) == state && err == nil {
+		return
+	}
+	if s.OnStateChange != nil {
+		s.OnStateChange(s, state, err)
+	}
+}
+
+func (s *Session) loop() {
+	defer func() {
+		s.mu.Lock()
+		s.queue = nil
+		s.mu.Unlock()
+		s.setState(enum.SESSION_CLOSED, nil)
+		close(s.done)
+	}()
+	attempt := 0
+	for s.ctx.Err() == nil {
+		s.setState(enum.SESSION_CONNECTING, nil)
+		conn, err := s.sms.Dial(s.addr, s.uid, s.pwd, s.timeout, s.ext)
+		if err == nil {
+			attempt = 0
+			c := conn.(*sms_conn)
+			c.EnabledActiveTest()
+			// 连接发布后才算登录完成
+			if s.bind(c) {
+				s.setState(enum.SESSION_BOUND, nil)
+				select {
+				case <-c.ctx.Done():
+				case <-s.ctx.Done():
+					s.unbind()
+					c.Close()
+					return
+				}
+				s.unbind()
+			} else {
+				err = smserror.ErrConnIsClosed
+			}
+		}
+		attempt++
+		s.setState(enum.SESSION_BACKOFF, err)
+		t := time.NewTimer(s.backoff(attempt))
+		select {
+		case <-t.C:
+		case <-s.ctx.Done():
+			t.Stop()
+			return
+		}
+	}
+}
+
+// bind 先发送排队中的报文,队列清空后再发布连接,保证发送顺序.
+// 连接断开时剩余报文放回队列并返回 false, 其他发送错误丢弃该报文并通过 OnError 通知
+func (s *Session) bind(c *sms_conn) bool {
+	for {
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		if len(queue) == 0 {
+			s.conn = c
+			close(s.ready)
+			s.mu.Unlock()
+			return true
+		}
+		s.mu.Unlock()
+		for i, pdu := range queue {
+			err := c.SendPDU(pdu)
+			if err == nil {
+				continue
+			}
+			if !c.IsConnected() {
+				s.mu.Lock()
+				s.queue = append(queue[i:], s.queue...)
+				s.mu.Unlock()
+				return false
+			}
+			s.sms.doError(c, fmt.Errorf("排队报文发送失败, 已丢弃: %w", err))
+		}
+	}
+}
+
+func (s *Session) unbind() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn = nil
+		s.ready = make(chan struct{})
+	}
+}
+
+// backoff 指数退避,加入随机抖动避免同时重连
+func (s *Session) backoff(attempt int) time.Duration {
+	d := s.minBackoff
+	for i := 1; i < attempt && d < s.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.maxBackoff {
+		d = s.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,54 @@
+package zysms
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/enum"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+func TestSessionBindDropsInvalidPDU(t *testing.T) {
+	srv, l := testListen(t, codec.SMPP34)
+	recv := make(chan PDU, 4)
+	srv.OnRecv = func(c Conn, p PDU) { recv <- p }
+
+	s := New(codec.SMPP34)
+	var dropped atomic.Int32
+	s.OnError = func(c Conn, err error) {
+		if errors.Is(err, smserror.ErrInvalidBindState) {
+			dropped.Add(1)
+		}
+	}
+	sess := s.NewSession(l.Addr().String(), testUid, testPwd, time.Second, map[string]string{"queue_size": "10", "bind_type": "tx"})
+	t.Cleanup(sess.Close)
+	var bound atomic.Bool
+	sess.OnStateChange = func(sess *Session, state int32, err error) {
+		if state == enum.SESSION_BOUND {
+			// 进入 BOUND 时连接已发布
+			bound.Store(sess.Conn() != nil)
+		}
+	}
+	// tx 方式登录不能发送 deliver_sm, 丢弃后继续发送后面的报文
+	require.NoError(t, sess.SendPDU(smpp.NewDeliverSM()))
+	submit := smpp.NewSubmitSM().(*smpp.SubmitSM)
+	require.NoError(t, sess.SendPDU(submit))
+	sess.Start()
+
+	require.Eventually(t, func() bool {
+		return sess.State() == enum.SESSION_BOUND && bound.Load()
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, int32(1), dropped.Load())
+	select {
+	case p := <-recv:
+		require.Equal(t, submit.GetSequenceNumber(), p.GetSequenceNumber())
+	case <-time.After(time.Second):
+		t.Fatal("queued submit_sm not sent")
+	}
+	require.NotNil(t, sess.Conn())
+}
@@ -2,6 +2,7 @@ package zysms
 
 import (
 	"fmt"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/zhiyin2021/zysms/enum"
 	"github.com/zhiyin2021/zysms/sgip"
 	"github.com/zhiyin2021/zysms/smserror"
+	"github.com/zhiyin2021/zysms/utils"
 )
 
 type sgip_action struct {
@@ -25,6 +27,9 @@ func (c *sgip_action) login(uid string, pwd string) error {
 	req.LoginName = uid
 	req.LoginPassword = pwd
 	req.LoginType = 1
+	if c.loginType != 0 {
+		req.LoginType = c.loginType
+	}
 	req.Version = c.Typ
 
 	err := c.SendPDU(req)
@@ -52,13 +57,13 @@ func (c *sgip_action) login(uid string, pwd string) error {
 	c.IsAuth = true
 	return nil
 }
-func (c *sgip_action) logout() {
-	c.SendPDU(sgip.NewUnbindReq(c.Typ, c.nodeId))
+func (c *sgip_action) logout() codec.PDU {
+	return sgip.NewUnbindReq(c.Typ, c.nodeId)
 }
 
 // RecvAndUnpackPkt receives sgip byte stream, and unpack it to some sgip packet structure.
 func (c *sgip_action) recv() (codec.PDU, error) {
-	if c.Connected == enum.CONN_DISCONNECTED {
+	if atomic.LoadInt32(&c.Connected) == enum.CONN_DISCONNECTED {
 		return nil, smserror.ErrConnIsClosed
 	}
 
@@ -66,20 +71,24 @@ func (c *sgip_action) recv() (codec.PDU, error) {
 	if err != nil {
 		return nil, err
 	}
+	c.releaseWindow(pdu)
 
+	// ReportReq 为状态报告, 与 DeliverReq 一样交给 OnRecv 响应
 	switch p := pdu.(type) {
-
-	case *sgip.ReportReq: // 当收到心跳请求,内部直接回复心跳,并递归继续获取数据
-		resp := p.GetResponse().(*sgip.ReportResp)
-		resp.Status = 0
-		c.SendPDU(resp)
-	case *sgip.ReportResp: // 当收到心跳回复,内部直接处理,并递归继续获取数据
+	case *sgip.ReportReq: // 空的 ReportReq 为心跳请求, 内部直接回复; 未登录时由 rejectByState 回复
+		if isHeartbeat(p) && c.checkBindState(p) == nil {
+			resp := p.GetResponse().(*sgip.ReportResp)
+			resp.Status = 0
+			c.SendPDU(resp)
+		}
+	case *sgip.ReportResp: // 当收到心跳回复,内部直接处理
 		atomic.StoreInt32(&c.counter, 0)
 	case *sgip.BindResp: // 当收到登录回复,内部先校验版本
 		if c.checkVer && p.Version != c.Typ {
 			return nil, fmt.Errorf("sgip version not match [ local: %d != remote: %d ]", c.Typ, p.Version)
 		}
 	case *sgip.UnbindReq: // 当收到退出请求,内部直接回复退出
+		c.setBindState(enum.BIND_UNBINDING)
 		resp := p.GetResponse()
 		c.SendPDU(resp)
 		time.Sleep(100 * time.Millisecond)
@@ -98,7 +107,110 @@ func (c *sgip_action) recv() (codec.PDU, error) {
 
 }
 
+// verify 校验 LoginName/LoginPassword
+func (c *sgip_action) verify(pdu codec.PDU, auth Authenticator) (codec.PDU, string, error) {
+	req, ok := pdu.(*sgip.BindReq)
+	if !ok {
+		return nil, "", nil
+	}
+	resp := req.GetResponse().(*sgip.BindResp)
+	fail := func(status sgip.Status) (codec.PDU, string, error) {
+		resp.Status = status
+		return resp, "", smserror.NewSmsErr(int(status), "sgip.login.error")
+	}
+	if c.IsAuth {
+		return fail(2)
+	}
+	if req.LoginType != 1 && req.LoginType != 2 {
+		return fail(4)
+	}
+	// SgipEndpoint 的反向连接只接受 SMG 登录
+	if c.loginType != 0 && req.LoginType != c.loginType {
+		return fail(4)
+	}
+	acc, err := auth.Lookup(c, req.LoginName)
+	if err != nil {
+		resp.Status = 11
+		return resp, "", err
+	}
+	if acc == nil || acc.Password != req.LoginPassword || !c.allowed(acc) || !c.certAllowed(req.LoginName) {
+		return fail(1)
+	}
+	if !c.register(req.LoginName) {
+		return fail(3)
+	}
+	return resp, req.LoginName, nil
+}
+
+func (c *sgip_action) submit(msg *Message) ([]codec.PDU, error) {
+	parts, err := splitText(msg)
+	if err != nil {
+		return nil, err
+	}
+	pdus := make([]codec.PDU, 0, len(parts))
+	for _, sm := range parts {
+		p := sgip.NewSubmitReq(c.Typ, c.nodeId).(*sgip.SubmitReq)
+		p.SPNumber = msg.From
+		p.UserCount = byte(len(msg.To))
+		p.UserNumber = msg.To
+		p.CorpId = utils.MapItem(c.extParam, "corp_id", "")
+		p.ServiceType = utils.MapItem(c.extParam, "service_id", "")
+		p.FeeType = 1
+		p.FeeValue = "0"
+		p.GivenValue = "0"
+		p.MorelatetoMTFlag = 2
+		p.ReportFlag = 2
+		if msg.RequestReport {
+			p.ReportFlag = 1
+		}
+		p.MessageCoding = sm.DataCoding()
+		if len(parts) > 1 {
+			p.TpUdhi = 1
+		}
+		p.Message = *sm
+		pdus = append(pdus, p)
+	}
+	return pdus, nil
+}
+
+// submitResult 消息ID为提交请求的序列号(节点编号+时间+序号)
+func (c *sgip_action) submitResult(resp codec.PDU) (string, error) {
+	p, ok := resp.(*sgip.SubmitResp)
+	if !ok {
+		return "", smserror.ErrRespNotMatch
+	}
+	if p.Status != 0 {
+		return "", smserror.NewSmsErr(int(p.Status), "sgip.submit.error")
+	}
+	return p.MsgId().String(), nil
+}
+
+// report 只处理 Submit 的最终状态, 等待发送的中间状态忽略
+func (c *sgip_action) report(pdu codec.PDU) (*Report, bool) {
+	p, ok := pdu.(*sgip.ReportReq)
+	if !ok || isHeartbeat(p) || p.ReportType != 0 || p.State == 1 {
+		return nil, false
+	}
+	r := &Report{
+		MsgID:    sgip.MsgId(p.SubmitSequenceNumber).String(),
+		Dest:     p.UserNumber,
+		Stat:     "DELIVRD",
+		DoneTime: time.Now().Format("0601021504"),
+		PDU:      p,
+	}
+	if p.State != 0 {
+		r.Stat, r.Err = "UNDELIV", strconv.Itoa(int(p.ErrorCode))
+	}
+	return r, true
+}
+
+// active_test sgip 没有链路检测命令, 以空的 ReportReq 作为心跳, SgipEndpoint 的连接不发送
 func (c *sgip_action) active_test() error {
 	p := sgip.NewReportReq(c.Typ, c.nodeId).(*sgip.ReportReq)
 	return c.SendPDU(p)
 }
+
+// isHeartbeat 未携带提交序列号及号码的 ReportReq 为心跳
+func isHeartbeat(p *sgip.ReportReq) bool {
+	return p.SubmitSequenceNumber == [3]uint32{} && p.UserNumber == ""
+}
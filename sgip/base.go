@@ -11,6 +11,7 @@ import (
 type base struct {
 	Header
 	OptionalParameters codec.OptionalFields
+	tlvOrder           codec.TlvOrder
 	Version            codec.Version
 }
 
@@ -18,9 +19,9 @@ func newBase(ver codec.Version, commandId codec.CommandId, seqId [3]uint32) (v b
 	v.OptionalParameters = make(codec.OptionalFields)
 	v.Version = ver
 	v.CommandID = commandId
-	if seqId[2] > 0 {
-		v.SequenceNumber = seqId
-	} else {
+	v.NodeId = seqId[0]
+	v.SequenceNumber = seqId
+	if seqId[2] == 0 {
 		v.AssignSequenceNumber()
 	}
 	return
@@ -77,6 +78,7 @@ func (c *base) unmarshalOptionalParam(optParam []byte) (err error) {
 		var field codec.Field
 		if err = field.Unmarshal(reader); err == nil {
 			c.OptionalParameters[field.Tag] = field
+			c.tlvOrder.Add(field.Tag)
 		} else {
 			return
 		}
@@ -95,10 +97,8 @@ func (c *base) marshal(b *codec.BytesWriter, bodyWriter func(*codec.BytesWriter)
 		bodyWriter(bodyBuf)
 	}
 
-	// optional body
-	for _, v := range c.OptionalParameters {
-		v.Marshal(bodyBuf)
-	}
+	// optional body, 按接收或注册的顺序
+	c.tlvOrder.Marshal(bodyBuf, c.OptionalParameters)
 
 	// write header
 	c.CommandLength = uint32(PDU_HEADER_SIZE + bodyBuf.Len())
@@ -111,6 +111,7 @@ func (c *base) marshal(b *codec.BytesWriter, bodyWriter func(*codec.BytesWriter)
 // RegisterOptionalParam register optional param.
 func (c *base) RegisterOptionalParam(tlv codec.Field) {
 	c.OptionalParameters[tlv.Tag] = tlv
+	c.tlvOrder.Add(tlv.Tag)
 }
 
 // IsOk is status ok.
@@ -3,7 +3,9 @@ package sgip
 import (
 	"encoding/binary"
 	"fmt"
+	"strconv"
 	"sync/atomic"
+	"time"
 
 	"github.com/zhiyin2021/zysms/codec"
 )
@@ -27,6 +29,10 @@ type Header struct {
 	NodeId         uint32
 }
 
+func (c *Header) GetCommandID() uint32 {
+	return uint32(c.CommandID)
+}
+
 // ParseHeader parses PDU header.
 func ParseHeader(v [20]byte) (h Header) {
 	h.CommandLength = binary.BigEndian.Uint32(v[:])
@@ -53,11 +59,46 @@ func (h *Header) Unmarshal(b *codec.BytesReader) error {
 
 var sequenceNumber int32
 
-// AssignSequenceNumber assigns sequence number auto-incrementally.
+// AssignSequenceNumber assigns sequence number auto-incrementally,
+// the second part is stamped with current time (MMDDhhmmss).
 func (c *Header) AssignSequenceNumber() {
+	c.SequenceNumber[1] = Timestamp(time.Now())
 	c.SetSequenceNumber(nextSequenceNumber(&sequenceNumber))
 }
 
+// MsgId returns the full sequence number (node id, timestamp, counter) as message id.
+func (c *Header) MsgId() MsgId {
+	return MsgId(c.SequenceNumber)
+}
+
+// Timestamp 序列号第二部分, 十进制的 MMDDhhmmss
+func Timestamp(t time.Time) uint32 {
+	return uint32(t.Month())*1e8 + uint32(t.Day())*1e6 + uint32(t.Hour())*1e4 + uint32(t.Minute())*1e2 + uint32(t.Second())
+}
+
+// MsgId SGIP 消息ID, 即提交请求的序列号: 节点编号, 时间(MMDDhhmmss), 序号
+type MsgId [3]uint32
+
+// String 三部分各补齐 10 位
+func (m MsgId) String() string {
+	return fmt.Sprintf("%010d%010d%010d", m[0], m[1], m[2])
+}
+
+// ParseMsgId 解析 String 格式的消息ID
+func ParseMsgId(s string) (m MsgId, err error) {
+	if len(s) != 30 {
+		return m, strconv.ErrSyntax
+	}
+	for i := range m {
+		n, err := strconv.ParseUint(s[i*10:i*10+10], 10, 32)
+		if err != nil {
+			return m, err
+		}
+		m[i] = uint32(n)
+	}
+	return m, nil
+}
+
 // ResetSequenceNumber resets sequence number.
 func (c *Header) ResetSequenceNumber() {
 	c.SequenceNumber[2] = 1
@@ -0,0 +1,151 @@
+package sgip
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+)
+
+func roundTrip(t *testing.T, p codec.PDU, size int) codec.PDU {
+	w := codec.NewWriter()
+	p.Marshal(w)
+	require.Equal(t, size, w.Len())
+
+	p1, err := Parse(bytes.NewReader(w.Bytes()), V12, 0)
+	require.NoError(t, err)
+	require.Equal(t, p, p1)
+	return p1
+}
+
+func TestReportReq(t *testing.T) {
+	p := NewReportReq(V12, 3010012345).(*ReportReq)
+	p.SubmitSequenceNumber = [3]uint32{3010012345, 1018081459, 2}
+	p.ReportType = 0
+	p.UserNumber = "8613800000001"
+	p.State = 2
+	p.ErrorCode = 25
+	p1 := roundTrip(t, p, 64).(*ReportReq)
+	require.Equal(t, "301001234510180814590000000002", MsgId(p1.SubmitSequenceNumber).String())
+
+	resp := p.GetResponse().(*ReportResp)
+	require.Equal(t, p.SequenceNumber, resp.SequenceNumber)
+	roundTrip(t, resp, 29)
+}
+
+func TestUserRptReq(t *testing.T) {
+	require.Equal(t, codec.CommandId(0x11), SGIP_USERRPT)
+	require.Equal(t, codec.CommandId(0x80000011), SGIP_USERRPT_RESP)
+	p := NewUserRptReq(V12, 3010012345).(*UserRptReq)
+	p.SPNumber = "10655"
+	p.UserNumber = "8613800000001"
+	p.UserCondition = 1
+	roundTrip(t, p, 71)
+
+	resp := p.GetResponse().(*UserRptResp)
+	resp.Status = 29
+	require.Equal(t, p.SequenceNumber, resp.SequenceNumber)
+	roundTrip(t, resp, 29)
+}
+
+func TestTraceReq(t *testing.T) {
+	p := NewTraceReq(V12, 3010012345).(*TraceReq)
+	p.SubmitSequenceNumber = [3]uint32{3010012345, 1018081459, 2}
+	p.UserNumber = "8613800000001"
+	roundTrip(t, p, 61)
+
+	resp := p.GetResponse().(*TraceResp)
+	resp.Items = []TraceItem{
+		{Result: 0, NodeId: "301001", ReceiveTime: "261018081459", SendTime: "261018081500"},
+		{Result: 21, NodeId: "301002", ReceiveTime: "261018081500", SendTime: "261018081501"},
+	}
+	require.Equal(t, p.SequenceNumber, resp.SequenceNumber)
+	roundTrip(t, resp, 21+2*47)
+
+	empty := NewTraceResp(V12, 0).(*TraceResp)
+	empty.Items = []TraceItem{}
+	roundTrip(t, empty, 21)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	w := codec.NewWriter()
+	h := Header{CommandLength: PDU_HEADER_SIZE, CommandID: SGIP_QUERYROUTE}
+	h.Marshal(w)
+	_, err := Parse(bytes.NewReader(w.Bytes()), V12, 0)
+	require.Error(t, err)
+}
+
+func TestMsgId(t *testing.T) {
+	tests := []struct {
+		id  MsgId
+		str string
+	}{
+		{MsgId{3010012345, 1018081459, 2}, "301001234510180814590000000002"},
+		{MsgId{0, 101000000, 1}, "000000000001010000000000000001"},
+		{MsgId{4294967295, 1231235959, 4294967295}, "429496729512312359594294967295"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.str, tt.id.String())
+		m, err := ParseMsgId(tt.str)
+		require.NoError(t, err)
+		require.Equal(t, tt.id, m)
+	}
+	for _, s := range []string{
+		"",
+		"30100123451018081459000000002",   // 长度不足
+		"3010012345101808145900000000021", // 超长
+		"30100123451018081459000000000x",
+		"429496729610180814590000000002", // 超出 uint32
+	} {
+		_, err := ParseMsgId(s)
+		require.Error(t, err, s)
+	}
+
+	// 序列号三部分经编码后保持不变
+	p := NewSubmitReq(V12, 3010012345).(*SubmitReq)
+	p.SPNumber = "10690001"
+	p.UserNumber = []string{"8613300000001"}
+	p.UserCount = 1
+	w := codec.NewWriter()
+	p.Marshal(w)
+	p1, err := Parse(bytes.NewReader(w.Bytes()), V12, 0)
+	require.NoError(t, err)
+	require.Equal(t, p.MsgId(), p1.(*SubmitReq).MsgId())
+	require.Equal(t, uint32(3010012345), p1.(*SubmitReq).MsgId()[0])
+}
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		t  time.Time
+		ts uint32
+	}{
+		{time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local), 101000000},
+		{time.Date(2026, 1, 9, 9, 9, 9, 0, time.Local), 109090909},
+		{time.Date(2026, 10, 18, 8, 14, 59, 0, time.Local), 1018081459},
+		{time.Date(2028, 2, 29, 12, 0, 0, 0, time.Local), 229120000},
+		{time.Date(2026, 12, 31, 23, 59, 59, 999, time.Local), 1231235959},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.ts, Timestamp(tt.t), tt.t)
+	}
+	// 小于 10 位时补齐
+	require.Equal(t, "0101000000", MsgId{0, Timestamp(tests[0].t), 0}.String()[10:20])
+}
+
+func TestSequenceNumberWrap(t *testing.T) {
+	old := atomic.LoadInt32(&sequenceNumber)
+	t.Cleanup(func() { atomic.StoreInt32(&sequenceNumber, old) })
+	atomic.StoreInt32(&sequenceNumber, 0x7FFFFFFF-1)
+
+	var h Header
+	h.AssignSequenceNumber()
+	require.Equal(t, int32(0x7FFFFFFF), h.GetSequenceNumber())
+	// 溢出后从 1 开始
+	h.AssignSequenceNumber()
+	require.Equal(t, int32(1), h.GetSequenceNumber())
+	h.AssignSequenceNumber()
+	require.Positive(t, h.GetSequenceNumber())
+}
@@ -4,11 +4,12 @@ import "github.com/zhiyin2021/zysms/codec"
 
 type ReportReq struct {
 	base
-	ReportType byte
-	UserNumber string
-	State      Status
-	ErrorCode  byte
-	Reserve    string
+	SubmitSequenceNumber [3]uint32 // 对应提交请求的序列号【 12 bytes 】
+	ReportType           byte      // 0: 对先前一条 Submit 的状态报告 1: 对先前一条前转 Deliver 的状态报告
+	UserNumber           string
+	State                Status // 0: 发送成功 1: 等待发送 2: 发送失败
+	ErrorCode            byte
+	Reserve              string
 }
 type ReportResp struct {
 	base
@@ -28,6 +29,9 @@ func NewReportResp(ver codec.Version, nodeId uint32) codec.PDU {
 }
 func (p *ReportReq) Marshal(w *codec.BytesWriter) {
 	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteU32(p.SubmitSequenceNumber[0])
+		bw.WriteU32(p.SubmitSequenceNumber[1])
+		bw.WriteU32(p.SubmitSequenceNumber[2])
 		bw.WriteByte(p.ReportType)
 		bw.WriteStr(p.UserNumber, 21)
 		bw.WriteByte(byte(p.State))
@@ -38,6 +42,9 @@ func (p *ReportReq) Marshal(w *codec.BytesWriter) {
 
 func (p *ReportReq) Unmarshal(w *codec.BytesReader) error {
 	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.SubmitSequenceNumber[0] = br.ReadU32()
+		p.SubmitSequenceNumber[1] = br.ReadU32()
+		p.SubmitSequenceNumber[2] = br.ReadU32()
 		p.ReportType = br.ReadU8()
 		p.UserNumber = br.ReadStr(21)
 		p.State = Status(br.ReadU8())
@@ -0,0 +1,96 @@
+package sgip
+
+import "github.com/zhiyin2021/zysms/codec"
+
+// TraceReq 跟踪某条 Submit 在各节点的处理情况【41 bytes】
+type TraceReq struct {
+	base
+	SubmitSequenceNumber [3]uint32 // 【12 bytes】被跟踪 Submit 的序列号
+	UserNumber           string    // 【21 bytes】被跟踪 Submit 的接收号码
+	Reserve              string    // 【8 bytes 】保留字段
+}
+
+// TraceResp 每个经过的节点一条 TraceItem
+type TraceResp struct {
+	base
+	Items []TraceItem
+}
+
+type TraceItem struct {
+	Result      Status // 【1 bytes 】0: 成功 其他: 失败
+	NodeId      string // 【6 bytes 】节点编号
+	ReceiveTime string // 【16 bytes】被跟踪消息到达该节点的时间 yymmddhhmmss
+	SendTime    string // 【16 bytes】消息离开该节点的时间 yymmddhhmmss
+	Reserve     string // 【8 bytes 】保留字段
+}
+
+func NewTraceReq(ver codec.Version, nodeId uint32) codec.PDU {
+	return &TraceReq{
+		base: newBase(ver, SGIP_TRACE, [3]uint32{nodeId, 0, 0}),
+	}
+}
+func NewTraceResp(ver codec.Version, nodeId uint32) codec.PDU {
+	return &TraceResp{
+		base: newBase(ver, SGIP_TRACE_RESP, [3]uint32{nodeId, 0, 0}),
+	}
+}
+func (p *TraceReq) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteU32(p.SubmitSequenceNumber[0])
+		bw.WriteU32(p.SubmitSequenceNumber[1])
+		bw.WriteU32(p.SubmitSequenceNumber[2])
+		bw.WriteStr(p.UserNumber, 21)
+		bw.WriteStr(p.Reserve, 8)
+	})
+}
+
+func (p *TraceReq) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.SubmitSequenceNumber[0] = br.ReadU32()
+		p.SubmitSequenceNumber[1] = br.ReadU32()
+		p.SubmitSequenceNumber[2] = br.ReadU32()
+		p.UserNumber = br.ReadStr(21)
+		p.Reserve = br.ReadStr(8)
+		return br.Err()
+	})
+}
+
+func (b *TraceReq) GetResponse() codec.PDU {
+	return &TraceResp{
+		base: newBase(b.Version, SGIP_TRACE_RESP, b.SequenceNumber),
+	}
+}
+
+func (p *TraceResp) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteByte(byte(len(p.Items)))
+		for _, v := range p.Items {
+			bw.WriteByte(byte(v.Result))
+			bw.WriteStr(v.NodeId, 6)
+			bw.WriteStr(v.ReceiveTime, 16)
+			bw.WriteStr(v.SendTime, 16)
+			bw.WriteStr(v.Reserve, 8)
+		}
+	})
+}
+
+func (p *TraceResp) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		count := int(br.ReadU8())
+		p.Items = make([]TraceItem, 0, count)
+		for i := 0; i < count && br.Err() == nil; i++ {
+			var v TraceItem
+			v.Result = Status(br.ReadU8())
+			v.NodeId = br.ReadStr(6)
+			v.ReceiveTime = br.ReadStr(16)
+			v.SendTime = br.ReadStr(16)
+			v.Reserve = br.ReadStr(8)
+			p.Items = append(p.Items, v)
+		}
+		return br.Err()
+	})
+}
+
+func (b *TraceResp) GetResponse() codec.PDU {
+	return nil
+}
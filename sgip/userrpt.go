@@ -0,0 +1,72 @@
+package sgip
+
+import "github.com/zhiyin2021/zysms/codec"
+
+// UserRptReq SMG 向 SP 报告手机用户状态变化【51 bytes】
+type UserRptReq struct {
+	base
+	SPNumber      string // 【21 bytes】SP 的接入号码
+	UserNumber    string // 【21 bytes】手机号码
+	UserCondition byte   // 【1 bytes 】0: 注销 1: 欠费停机 2: 恢复正常
+	Reserve       string // 【8 bytes 】保留字段
+}
+
+type UserRptResp struct {
+	base
+	Status  Status
+	Reserve string
+}
+
+func NewUserRptReq(ver codec.Version, nodeId uint32) codec.PDU {
+	return &UserRptReq{
+		base: newBase(ver, SGIP_USERRPT, [3]uint32{nodeId, 0, 0}),
+	}
+}
+func NewUserRptResp(ver codec.Version, nodeId uint32) codec.PDU {
+	return &UserRptResp{
+		base: newBase(ver, SGIP_USERRPT_RESP, [3]uint32{nodeId, 0, 0}),
+	}
+}
+func (p *UserRptReq) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteStr(p.SPNumber, 21)
+		bw.WriteStr(p.UserNumber, 21)
+		bw.WriteByte(p.UserCondition)
+		bw.WriteStr(p.Reserve, 8)
+	})
+}
+
+func (p *UserRptReq) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.SPNumber = br.ReadStr(21)
+		p.UserNumber = br.ReadStr(21)
+		p.UserCondition = br.ReadU8()
+		p.Reserve = br.ReadStr(8)
+		return br.Err()
+	})
+}
+
+func (b *UserRptReq) GetResponse() codec.PDU {
+	return &UserRptResp{
+		base: newBase(b.Version, SGIP_USERRPT_RESP, b.SequenceNumber),
+	}
+}
+
+func (p *UserRptResp) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteByte(byte(p.Status))
+		bw.WriteStr(p.Reserve, 8)
+	})
+}
+
+func (p *UserRptResp) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.Status = Status(br.ReadU8())
+		p.Reserve = br.ReadStr(8)
+		return br.Err()
+	})
+}
+
+func (b *UserRptResp) GetResponse() codec.PDU {
+	return nil
+}
@@ -21,9 +21,27 @@ const (
 	SGIP_SUBMIT, SGIP_SUBMIT_RESP
 	SGIP_DELIVER, SGIP_DELIVER_RESP
 	SGIP_REPORT, SGIP_REPORT_RESP
+	// SMG 之间及 SMG 与 GNS 之间的命令
+	SGIP_ADDSP, SGIP_ADDSP_RESP
+	SGIP_MODIFYSP, SGIP_MODIFYSP_RESP
+	SGIP_DELETESP, SGIP_DELETESP_RESP
+	SGIP_QUERYROUTE, SGIP_QUERYROUTE_RESP
+	SGIP_ADDTELESEG, SGIP_ADDTELESEG_RESP
+	SGIP_MODIFYTELESEG, SGIP_MODIFYTELESEG_RESP
+	SGIP_DELETETELESEG, SGIP_DELETETELESEG_RESP
+	SGIP_ADDSMG, SGIP_ADDSMG_RESP
+	SGIP_MODIFYSMG, SGIP_MODIFYSMG_RESP
+	SGIP_DELETESMG, SGIP_DELETESMG_RESP
+	SGIP_CHECKUSER, SGIP_CHECKUSER_RESP
+	SGIP_USERRPT, SGIP_USERRPT_RESP
 	SGIP_REQUEST_MAX, SGIP_RESPONSE_MAX
 )
 
+const (
+	SGIP_TRACE      codec.CommandId = 0x00001000
+	SGIP_TRACE_RESP codec.CommandId = 0x80001000
+)
+
 // func (id CommandId) OpLog() log.Field {
 // 	return log.String("op", id.String())
 // }
@@ -85,6 +103,14 @@ func CreatePDUHeader(header Header, ver codec.Version) (codec.PDU, error) {
 		return &ReportReq{base: base}, nil
 	case SGIP_REPORT_RESP:
 		return &ReportResp{base: base}, nil
+	case SGIP_USERRPT:
+		return &UserRptReq{base: base}, nil
+	case SGIP_USERRPT_RESP:
+		return &UserRptResp{base: base}, nil
+	case SGIP_TRACE:
+		return &TraceReq{base: base}, nil
+	case SGIP_TRACE_RESP:
+		return &TraceResp{base: base}, nil
 	default:
 		return nil, smserror.ErrUnknownCommandID
 	}
@@ -0,0 +1,83 @@
+package zysms
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/sgip"
+)
+
+func TestSgipHeartbeat(t *testing.T) {
+	s, l := testListen(t, codec.SGIP)
+	var heartbeats atomic.Int32
+	s.OnRecv = func(c Conn, p PDU) {
+		if r, ok := p.(*sgip.ReportReq); ok && isHeartbeat(r) {
+			heartbeats.Add(1)
+		}
+	}
+	s.OnReport = func(Conn, *Receipt, *Report) {
+		t.Error("heartbeat matched as report")
+	}
+	s.Receipts = NewMemoryReceiptStore(time.Minute)
+
+	_, c := testDial(t, codec.SGIP, l, nil)
+	n, err := c.sendActiveTest()
+	require.NoError(t, err)
+	require.Equal(t, int32(1), n)
+	// 服务端回复 ReportResp 后计数清零
+	require.Eventually(t, func() bool {
+		return heartbeats.Load() == 1 && atomic.LoadInt32(&c.counter) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSgipEndpointNoHeartbeat(t *testing.T) {
+	s := New(codec.SGIP)
+	e, err := s.NewSgipEndpoint("127.0.0.1:1", testUid, testPwd, "smg", "smgpwd", time.Second, nil)
+	require.NoError(t, err)
+	require.Equal(t, "0", e.session.ext["active_interval"])
+	require.NoError(t, e.Start("127.0.0.1:0"))
+	t.Cleanup(e.Close)
+
+	smg := New(codec.SGIP)
+	conn, err := smg.Dial(e.Listener().Addr().String(), "smg", "smgpwd", time.Second, map[string]string{"login_type": "2"})
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownNow(smg) })
+	require.Equal(t, int32(5), conn.(*sms_conn).activeInterval)
+
+	in := serverConn(t, e.Listener())
+	require.Equal(t, int32(0), in.activeInterval)
+}
+
+func TestSgipNodeId(t *testing.T) {
+	s, l := testListen(t, codec.SGIP)
+	submits := make(chan *sgip.SubmitReq, 2)
+	s.OnRecv = func(c Conn, p PDU) {
+		if r, ok := p.(*sgip.SubmitReq); ok {
+			submits <- r
+			c.SendPDU(r.GetResponse())
+		}
+	}
+	tests := []struct {
+		ext    map[string]string
+		nodeId uint32
+	}{
+		// 未设置时使用 SMS.NodeId
+		{nil, 0},
+		{map[string]string{"node_id": "3010012345"}, 3010012345},
+	}
+	for _, tt := range tests {
+		_, c := testDial(t, codec.SGIP, l, tt.ext)
+		before := sgip.Timestamp(time.Now())
+		ids, err := c.SendText(context.Background(), Message{From: "10690001", To: []string{"8613300000001"}, Text: "hi"})
+		require.NoError(t, err)
+		p := <-submits
+		id := p.MsgId()
+		require.Equal(t, tt.nodeId, id[0])
+		require.GreaterOrEqual(t, id[1], before)
+		require.Equal(t, []string{id.String()}, ids)
+	}
+}
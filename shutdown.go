@@ -0,0 +1,87 @@
+package zysms
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhiyin2021/zysms/enum"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+// Shutdown 停止所有监听, 已建立的连接等待请求完成后退出登录, ctx 结束时强制关闭.
+// 之后 Dial 返回 ErrServerClosed, Session 需单独 Close
+func (s *SMS) Shutdown(ctx context.Context) error {
+	s.listenerMu.Lock()
+	listeners := s.listeners
+	s.listeners = nil
+	s.listenerMu.Unlock()
+	for _, l := range listeners {
+		l.Listener.Close()
+	}
+	shutdownAll(ctx, s.conns.close())
+	return ctx.Err()
+}
+
+// Shutdown 停止接受新连接, 该监听上的连接等待请求完成后退出登录, ctx 结束时强制关闭
+func (l *Listener) Shutdown(ctx context.Context) error {
+	l.parent.listenerMu.Lock()
+	l.parent.listeners = slices.DeleteFunc(l.parent.listeners, func(v *Listener) bool { return v == l })
+	l.parent.listenerMu.Unlock()
+	err := l.Listener.Close()
+	shutdownAll(ctx, l.conns.close())
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func shutdownAll(ctx context.Context, conns []*sms_conn) {
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		wg.Add(1)
+		tryGO(func() {
+			defer wg.Done()
+			c.shutdown(ctx, true)
+		})
+	}
+	wg.Wait()
+}
+
+// shutdown 已登录时进入 BIND_UNBINDING 拒绝新的请求, drain 时先等待已发送的请求收到响应,
+// 然后发送退出请求并等待响应, ctx 结束时直接关闭
+func (c *sms_conn) shutdown(ctx context.Context, drain bool) {
+	if c.unbind() {
+		if !drain || c.drain(ctx) == nil {
+			c.Request(ctx, c.action.logout())
+		}
+	}
+	c.close()
+}
+
+// unbind 已登录时进入 BIND_UNBINDING, 返回是否需要发送退出请求
+func (c *sms_conn) unbind() bool {
+	state := c.BindState()
+	return c.IsAuth && c.action != nil && c.IsConnected() &&
+		state != enum.BIND_UNBINDING && state != enum.BIND_CLOSED &&
+		atomic.CompareAndSwapInt32(&c.bindState, state, enum.BIND_UNBINDING)
+}
+
+// drain 等待正在发送的请求发出, 已发送的请求收到响应.
+// 未设置窗口时 SendPDU 发送的请求没有记录, 只等待 Request/SendAsync 的响应
+func (c *sms_conn) drain(ctx context.Context) error {
+	t := time.NewTicker(10 * time.Millisecond)
+	defer t.Stop()
+	for c.InFlight() > 0 || atomic.LoadInt32(&c.sending) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.ctx.Done():
+			return smserror.ErrConnIsClosed
+		case <-t.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package zysms
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+)
+
+func TestShutdownDrain(t *testing.T) {
+	s, l := testListen(t, codec.CMPP30)
+	var submits atomic.Int32
+	s.OnRecv = func(c Conn, p PDU) {
+		if req, ok := p.(*cmpp.SubmitReq); ok {
+			submits.Add(1)
+			time.AfterFunc(10*time.Millisecond, func() { c.SendPDU(req.GetResponse()) })
+		}
+	}
+	smg, c := testDial(t, codec.CMPP30, l, map[string]string{"tps": "5", "burst": "1"})
+
+	f, err := c.SendAsync(cmpp.NewSubmitReq(cmpp.V30))
+	require.NoError(t, err)
+	// 等待限速中的 SendPDU 同样在退出前发出
+	sent := make(chan error, 1)
+	go func() { sent <- c.SendPDU(cmpp.NewSubmitReq(cmpp.V30)) }()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&c.sending) == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, smg.Shutdown(ctx))
+	_, err = f.Result()
+	require.NoError(t, err)
+	require.NoError(t, <-sent)
+	require.Equal(t, int32(2), submits.Load())
+	require.False(t, c.IsConnected())
+}
+
+func TestListenerShutdown(t *testing.T) {
+	s, l := testListen(t, codec.CMPP30)
+	l2, err := s.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, l2.Shutdown(context.Background()))
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+	require.Equal(t, []*Listener{l}, s.listeners)
+}
+
+func TestCloseInRecv(t *testing.T) {
+	s, l := testListen(t, codec.CMPP30)
+	closed := make(chan time.Duration, 1)
+	s.OnRecv = func(c Conn, p PDU) {
+		if _, ok := p.(*cmpp.SubmitReq); ok {
+			start := time.Now()
+			c.Close()
+			closed <- time.Since(start)
+		}
+	}
+	_, c := testDial(t, codec.CMPP30, l, nil)
+	require.NoError(t, c.SendPDU(cmpp.NewSubmitReq(cmpp.V30)))
+	// 在回调中关闭不等待退出响应
+	require.Less(t, <-closed, logoutTimeout/2)
+	require.Eventually(t, func() bool { return !c.IsConnected() }, time.Second, 5*time.Millisecond)
+}
@@ -1,7 +1,13 @@
 package zysms
 
 import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -9,6 +15,7 @@ import (
 	"github.com/zhiyin2021/zysms/enum"
 	"github.com/zhiyin2021/zysms/smgp"
 	"github.com/zhiyin2021/zysms/smserror"
+	"github.com/zhiyin2021/zysms/utils"
 )
 
 type smgp_action struct {
@@ -51,20 +58,37 @@ func (c *smgp_action) login(uid string, pwd string) error {
 	c.IsAuth = true
 	return nil
 }
-func (c *smgp_action) logout() {
-	c.SendPDU(smgp.NewExitReq(c.Typ))
+func (c *smgp_action) logout() codec.PDU {
+	return smgp.NewExitReq(c.Typ)
 }
 
 // RecvAndUnpackPkt receives smgp byte stream, and unpack it to some smgp packet structure.
 func (c *smgp_action) recv() (codec.PDU, error) {
-	if c.Connected == enum.CONN_DISCONNECTED {
+	if atomic.LoadInt32(&c.Connected) == enum.CONN_DISCONNECTED {
 		return nil, smserror.ErrConnIsClosed
 	}
 
-	pdu, err := smgp.Parse(c.Conn, c.Typ, c.logger)
+	var pdu codec.PDU
+	var err error
+	for {
+		pdu, err = smgp.Parse(c.Conn, c.Typ, c.logger)
+		if !errors.Is(err, smserror.ErrInvalidTlv) || pdu == nil {
+			break
+		}
+		// 可选参数长度不符, 请求回复 消息结构错 后继续读取,
+		// 其他报文照常处理, 状态不允许的请求由 rejectByState 回复
+		c.logger.Warnf("recv %T: %v", pdu, err)
+		resp := invalidTlvResp(pdu)
+		if resp == nil || c.checkBindState(pdu) != nil {
+			err = nil
+			break
+		}
+		c.SendPDU(resp)
+	}
 	if err != nil {
 		return nil, err
 	}
+	c.releaseWindow(pdu)
 
 	switch p := pdu.(type) {
 	case *smgp.ActiveTestReq: // 当收到心跳请求,内部直接回复心跳,并递归继续获取数据
@@ -80,10 +104,16 @@ func (c *smgp_action) recv() (codec.PDU, error) {
 			return nil, fmt.Errorf("smgp version not match [ local: %d != remote: %d ]", c.Typ, p.Version)
 		}
 	case *smgp.ExitReq:
+		c.setBindState(enum.BIND_UNBINDING)
 		resp := p.GetResponse()
 		c.SendPDU(resp)
 		time.Sleep(100 * time.Millisecond)
 		return nil, smserror.ErrConnIsClosed
+	case *smgp.MTRouteUpdateReq, *smgp.MORouteUpdateReq, *smgp.QueryReq, *smgp.ForwardReq:
+		// 路由更新、查询及转发由 OnRecv 处理, 未设置时回复不支持
+		if c.parent.OnRecv == nil && c.checkBindState(p) == nil {
+			c.SendPDU(unsupportedResp(p))
+		}
 	case *smgp.LoginReq:
 		switch p.Version {
 		case smgp.V20, smgp.V30:
@@ -100,6 +130,157 @@ func (c *smgp_action) recv() (codec.PDU, error) {
 
 }
 
+// unsupportedResp 未处理的请求的响应: 路由更新 68 本节点不支持路由更新, 转发 11 命令字错,
+// 查询没有状态字段, 返回统计数量为 0 的响应
+func unsupportedResp(pdu codec.PDU) codec.PDU {
+	resp := pdu.GetResponse()
+	switch p := resp.(type) {
+	case *smgp.MTRouteUpdateResp:
+		p.Status = 68
+	case *smgp.MORouteUpdateResp:
+		p.Status = 68
+	case *smgp.ForwardResp:
+		p.Status = 11
+	}
+	return resp
+}
+
+// invalidTlvResp 可选参数错误时的响应, 只有带 Status 的请求需要回复
+func invalidTlvResp(pdu codec.PDU) codec.PDU {
+	switch resp := pdu.GetResponse().(type) {
+	case *smgp.SubmitResp:
+		resp.Status = 10
+		return resp
+	case *smgp.DeliverResp:
+		resp.Status = 10
+		return resp
+	case *smgp.ForwardResp:
+		resp.Status = 10
+		return resp
+	}
+	return nil
+}
+
+// verify AuthenticatorClient = MD5(ClientID + 7字节0 + secret + timestamp)
+// AuthenticatorServer = MD5(Status + AuthenticatorClient + secret)
+func (c *smgp_action) verify(pdu codec.PDU, auth Authenticator) (codec.PDU, string, error) {
+	req, ok := pdu.(*smgp.LoginReq)
+	if !ok {
+		return nil, "", nil
+	}
+	resp := req.GetResponse().(*smgp.LoginResp)
+	fail := func(status smgp.Status) (codec.PDU, string, error) {
+		resp.Status = status
+		return resp, "", smserror.NewSmsErr(int(status), "smgp.login.error")
+	}
+	if c.IsAuth {
+		// smgp 没有重复登录的状态码, 2 用于超过最大连接数, 重复登录回复认证错
+		return fail(21)
+	}
+	acc, err := auth.Lookup(c, req.ClientID)
+	if err != nil {
+		resp.Status = 1
+		return resp, "", err
+	}
+	if acc == nil {
+		return fail(21)
+	}
+	authClient := md5.Sum(bytes.Join([][]byte{[]byte(req.ClientID),
+		make([]byte, 7),
+		[]byte(acc.Password),
+		[]byte(utils.Timestamp2Str(req.Timestamp))},
+		nil))
+	if req.AuthenticatorClient != strings.TrimRight(string(authClient[:]), "\x00") {
+		return fail(21)
+	}
+	if !c.certAllowed(req.ClientID) {
+		return fail(21)
+	}
+	if !c.allowed(acc) {
+		return fail(20)
+	}
+	status := make([]byte, 4)
+	binary.BigEndian.PutUint32(status, uint32(resp.Status))
+	authServer := md5.Sum(bytes.Join([][]byte{status,
+		authClient[:],
+		[]byte(acc.Password)},
+		nil))
+	resp.AuthenticatorServer = string(authServer[:])
+	if !c.register(req.ClientID) {
+		return fail(2)
+	}
+	return resp, req.ClientID, nil
+}
+
+func (c *smgp_action) submit(msg *Message) ([]codec.PDU, error) {
+	parts, err := splitText(msg)
+	if err != nil {
+		return nil, err
+	}
+	pdus := make([]codec.PDU, 0, len(parts))
+	for i, sm := range parts {
+		p := smgp.NewSubmitReq(c.Typ).(*smgp.SubmitReq)
+		p.SubType = 6 // MT
+		if msg.RequestReport {
+			p.NeedReport = 1
+		}
+		p.Priority = 1
+		p.ServiceID = utils.MapItem(c.extParam, "service_id", "")
+		p.FeeType = "00"
+		p.FeeCode = "0"
+		p.FixedFee = "0"
+		p.MsgFormat = sm.DataCoding()
+		p.SrcTermID = msg.From
+		p.DestTermID = msg.To
+		p.Message = *sm
+		if len(parts) > 1 {
+			p.SetTpUdhi(true)
+			p.SetPkTotal(byte(len(parts)))
+			p.SetPkNumber(byte(i + 1))
+		}
+		pdus = append(pdus, p)
+	}
+	return pdus, nil
+}
+
+// submitResult MsgId 为10字节, 以16进制表示
+func (c *smgp_action) submitResult(resp codec.PDU) (string, error) {
+	p, ok := resp.(*smgp.SubmitResp)
+	if !ok {
+		return "", smserror.ErrRespNotMatch
+	}
+	if p.Status != 0 {
+		return "", smserror.NewSmsErr(int(p.Status), "smgp.submit.error")
+	}
+	id := make([]byte, 10)
+	copy(id, p.MsgId)
+	return hex.EncodeToString(id), nil
+}
+
+// report 报告中的 id 一般为10字节原始值, 也有网关填写16进制字符串
+func (c *smgp_action) report(pdu codec.PDU) (*Report, bool) {
+	p, ok := pdu.(*smgp.DeliverReq)
+	if !ok || p.IsReport != 1 || p.Report == nil {
+		return nil, false
+	}
+	r := p.Report
+	id := strings.ToLower(r.MsgId)
+	if len(r.MsgId) <= 10 {
+		b := make([]byte, 10)
+		copy(b, r.MsgId)
+		id = hex.EncodeToString(b)
+	}
+	return &Report{
+		MsgID:      id,
+		Dest:       p.SrcTermID,
+		Stat:       r.Stat,
+		Err:        r.Err,
+		SubmitTime: r.SubmitDate,
+		DoneTime:   r.DoneDate,
+		PDU:        p,
+	}, true
+}
+
 func (c *smgp_action) active_test() error {
 	p := smgp.NewActiveTestReq(c.Typ)
 	c.activeTestReq(p.GetSequenceNumber())
@@ -11,6 +11,7 @@ import (
 type base struct {
 	Header
 	OptionalParameters codec.OptionalFields
+	tlvOrder           codec.TlvOrder
 	Version            codec.Version
 }
 
@@ -73,15 +74,19 @@ func (c *base) unmarshal(b *codec.BytesReader, bodyReader func(*codec.BytesReade
 	return
 }
 
+// unmarshalOptionalParam 长度不符的参数仍然保留, 读完后返回第一个错误
 func (c *base) unmarshalOptionalParam(optParam []byte) (err error) {
 	reader := codec.NewReader(optParam)
 	for reader.Len() > 0 {
 		var field codec.Field
-		if err = field.Unmarshal(reader); err == nil {
-			c.OptionalParameters[field.Tag] = field
-		} else {
-			return
+		if e := field.Unmarshal(reader); e != nil {
+			return e
+		}
+		if e := ValidateTlv(field); e != nil && err == nil {
+			err = e
 		}
+		c.OptionalParameters[field.Tag] = field
+		c.tlvOrder.Add(field.Tag)
 	}
 	return
 }
@@ -96,10 +101,8 @@ func (c *base) marshal(b *codec.BytesWriter, bodyWriter func(*codec.BytesWriter)
 		bodyWriter(bodyBuf)
 	}
 
-	// optional body
-	for _, v := range c.OptionalParameters {
-		v.Marshal(bodyBuf)
-	}
+	// optional body, 按接收或注册的顺序
+	c.tlvOrder.Marshal(bodyBuf, c.OptionalParameters)
 
 	// write header
 	c.CommandLength = uint32(PDU_HEADER_SIZE + bodyBuf.Len())
@@ -112,6 +115,7 @@ func (c *base) marshal(b *codec.BytesWriter, bodyWriter func(*codec.BytesWriter)
 // RegisterOptionalParam register optional param.
 func (c *base) RegisterOptionalParam(tlv codec.Field) {
 	c.OptionalParameters[tlv.Tag] = tlv
+	c.tlvOrder.Add(tlv.Tag)
 }
 
 // IsOk is status ok.
@@ -132,7 +136,7 @@ func Parse(r io.Reader, ver codec.Version, logger *zap.SugaredLogger) (pdu codec
 			err = smserror.ErrInvalidPDU
 		}
 	}()
-	var headerBytes [16]byte
+	var headerBytes [PDU_HEADER_SIZE]byte
 
 	if _, err = io.ReadFull(r, headerBytes[:]); err != nil {
 		return
@@ -145,7 +149,7 @@ func Parse(r io.Reader, ver codec.Version, logger *zap.SugaredLogger) (pdu codec
 	}
 
 	// read pdu body
-	bodyBytes := make([]byte, header.CommandLength-16)
+	bodyBytes := make([]byte, header.CommandLength-PDU_HEADER_SIZE)
 	if len(bodyBytes) > 0 {
 		if _, err = io.ReadFull(r, bodyBytes); err != nil {
 			return
@@ -49,6 +49,9 @@ func NewDeliverResp(ver codec.Version) codec.PDU {
 
 // Pack packs the ActiveTestReq to bytes stream for client side.
 func (p *DeliverReq) Marshal(w *codec.BytesWriter) {
+	if p.Report == nil && hasUDH(&p.Message) {
+		p.SetTpUdhi(true)
+	}
 	p.base.marshal(w, func(bw *codec.BytesWriter) {
 		bw.WriteStr(p.MsgId, 10)
 		if p.Report != nil {
@@ -69,21 +72,28 @@ func (p *DeliverReq) Marshal(w *codec.BytesWriter) {
 // Unpack unpack the binary byte stream to a ActiveTestReq variable.
 // After unpack, you will get all value of fields in
 // ActiveTestReq struct.
-func (p *DeliverReq) Unmarshal(w *codec.BytesReader) error {
-	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+func (p *DeliverReq) Unmarshal(w *codec.BytesReader) (err error) {
+	var msg []byte
+	err = p.base.unmarshal(w, func(br *codec.BytesReader) error {
 		p.MsgId = br.ReadStr(10)
 		p.IsReport = br.ReadU8()
 		p.MsgFormat = br.ReadU8()
 		p.RecvTime = br.ReadStr(14)
 		p.SrcTermID = br.ReadStr(21)
 		p.DestTermID = br.ReadStr(21)
-		p.Message.Unmarshal(br, false, p.MsgFormat)
-		if p.IsReport == 1 {
-			p.decodeReport()
-		}
+		msg = readMessage(br)
 		p.Reserve = br.ReadStr(8)
 		return br.Err()
 	})
+	if err != nil {
+		return err
+	}
+	if p.IsReport == 1 {
+		p.Message.Unmarshal(codec.NewReader(msg), false, p.MsgFormat)
+		p.decodeReport()
+		return nil
+	}
+	return p.decodeMessage(&p.Message, msg, p.MsgFormat)
 }
 
 // GetResponse implements PDU interface.
@@ -0,0 +1,136 @@
+package smgp
+
+import (
+	"github.com/zhiyin2021/zysms/codec"
+)
+
+// ForwardReq 网关之间转发短消息
+type ForwardReq struct {
+	base
+	DestSMGWNo   string // 【6字节】目的网关代码
+	SrcSMGWNo    string // 【6字节】源网关代码
+	SmcNo        string // 【6字节】短消息中心代码
+	MsgId        string // 【10字节】短消息流水号
+	MsgType      byte   // 【1字节】短消息类型
+	NeedReport   byte   // 【1字节】是否要求返回状态报告
+	Priority     byte   // 【1字节】短消息发送优先级
+	ServiceID    string // 【10字节】业务代码
+	FeeType      string // 【2字节】收费类型
+	FeeCode      string // 【6字节】资费代码
+	FixedFee     string // 【6字节】包月费/封顶费
+	MsgFormat    byte   // 【1字节】短消息格式
+	ValidTime    string // 【17字节】短消息有效时间
+	AtTime       string // 【17字节】短消息定时发送时间
+	SrcTermID    string // 【21字节】短信息发送方号码
+	DestTermID   string // 【21字节】短消息接收号码
+	ChargeTermID string // 【21字节】计费用户号码
+
+	Message codec.ShortMessage // 消息内容按照Msg_Fmt编码后的数据
+	Reserve string             // 【8字节】保留
+}
+
+type ForwardResp struct {
+	base
+	MsgId  string // 【10字节】短消息流水号
+	Status Status
+}
+
+func NewForwardReq(ver codec.Version) codec.PDU {
+	return &ForwardReq{
+		base: newBase(ver, SMGP_FORWARD, 0),
+	}
+}
+func NewForwardResp(ver codec.Version) codec.PDU {
+	return &ForwardResp{
+		base: newBase(ver, SMGP_FORWARD_RESP, 0),
+	}
+}
+
+// Pack packs the ForwardReq to bytes stream.
+func (p *ForwardReq) Marshal(w *codec.BytesWriter) {
+	if hasUDH(&p.Message) {
+		p.setTpUdhi(true)
+	}
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteStr(p.DestSMGWNo, 6)
+		bw.WriteStr(p.SrcSMGWNo, 6)
+		bw.WriteStr(p.SmcNo, 6)
+		bw.WriteStr(p.MsgId, 10)
+		bw.WriteByte(p.MsgType)
+		bw.WriteByte(p.NeedReport)
+		bw.WriteByte(p.Priority)
+		bw.WriteStr(p.ServiceID, 10)
+		bw.WriteStr(p.FeeType, 2)
+		bw.WriteStr(p.FeeCode, 6)
+		bw.WriteStr(p.FixedFee, 6)
+		bw.WriteByte(p.MsgFormat)
+		bw.WriteStr(p.ValidTime, 17)
+		bw.WriteStr(p.AtTime, 17)
+		bw.WriteStr(p.SrcTermID, 21)
+		bw.WriteStr(p.DestTermID, 21)
+		bw.WriteStr(p.ChargeTermID, 21)
+		p.Message.Marshal(bw)
+		bw.WriteStr(p.Reserve, 8)
+	})
+}
+
+// Unpack unpack the binary byte stream to a ForwardReq variable.
+func (p *ForwardReq) Unmarshal(w *codec.BytesReader) (err error) {
+	var msg []byte
+	err = p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.DestSMGWNo = br.ReadStr(6)
+		p.SrcSMGWNo = br.ReadStr(6)
+		p.SmcNo = br.ReadStr(6)
+		p.MsgId = br.ReadStr(10)
+		p.MsgType = br.ReadU8()
+		p.NeedReport = br.ReadU8()
+		p.Priority = br.ReadU8()
+		p.ServiceID = br.ReadStr(10)
+		p.FeeType = br.ReadStr(2)
+		p.FeeCode = br.ReadStr(6)
+		p.FixedFee = br.ReadStr(6)
+		p.MsgFormat = br.ReadU8()
+		p.ValidTime = br.ReadStr(17)
+		p.AtTime = br.ReadStr(17)
+		p.SrcTermID = br.ReadStr(21)
+		p.DestTermID = br.ReadStr(21)
+		p.ChargeTermID = br.ReadStr(21)
+		msg = readMessage(br)
+		p.Reserve = br.ReadStr(8)
+		return br.Err()
+	})
+	if err == nil {
+		err = p.decodeMessage(&p.Message, msg, p.MsgFormat)
+	}
+	return err
+}
+
+// GetResponse implements PDU interface.
+func (b *ForwardReq) GetResponse() codec.PDU {
+	return &ForwardResp{
+		base:  newBase(b.Version, SMGP_FORWARD_RESP, b.SequenceNumber),
+		MsgId: b.MsgId,
+	}
+}
+
+// Pack packs the ForwardResp to bytes stream.
+func (p *ForwardResp) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteStr(p.MsgId, 10)
+		bw.WriteU32(uint32(p.Status))
+	})
+}
+
+// Unpack unpack the binary byte stream to a ForwardResp variable.
+func (p *ForwardResp) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.MsgId = br.ReadStr(10)
+		p.Status = Status(br.ReadU32())
+		return br.Err()
+	})
+}
+
+// GetResponse implements PDU interface.
+func (b *ForwardResp) GetResponse() codec.PDU {
+	return nil
+}
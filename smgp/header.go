@@ -30,8 +30,12 @@ func (c Header) String() string {
 	return fmt.Sprintf("0x%.8d,%d,%d", c.CommandID, c.SequenceNumber, c.CommandLength)
 }
 
+func (c *Header) GetCommandID() uint32 {
+	return uint32(c.CommandID)
+}
+
 // ParseHeader parses PDU header.
-func ParseHeader(v [16]byte) (h Header) {
+func ParseHeader(v [PDU_HEADER_SIZE]byte) (h Header) {
 	h.CommandLength = binary.BigEndian.Uint32(v[:])
 	h.CommandID = codec.CommandId(binary.BigEndian.Uint32(v[4:]))
 	h.SequenceNumber = int32(binary.BigEndian.Uint32(v[8:]))
@@ -46,12 +46,15 @@ func (p *LoginReq) Marshal(w *codec.BytesWriter) {
 	} else {
 		ts = utils.Timestamp2Str(p.Timestamp)
 	}
-	md5 := md5.Sum(bytes.Join([][]byte{[]byte(p.ClientID),
-		make([]byte, 7),
-		[]byte(p.Secret),
-		[]byte(ts)},
-		nil))
-	p.AuthenticatorClient = string(md5[:])
+	// 已有认证码且无密钥时(如解析所得)保持原值, 保证重新编码字节一致
+	if p.Secret != "" || p.AuthenticatorClient == "" {
+		md5 := md5.Sum(bytes.Join([][]byte{[]byte(p.ClientID),
+			make([]byte, 7),
+			[]byte(p.Secret),
+			[]byte(ts)},
+			nil))
+		p.AuthenticatorClient = string(md5[:])
+	}
 
 	p.base.marshal(w, func(bw *codec.BytesWriter) {
 		bw.WriteStr(p.ClientID, 8)
@@ -0,0 +1,223 @@
+package smgp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smserror"
+	"github.com/zhiyin2021/zysms/utils/logger"
+)
+
+func roundTrip(t *testing.T, p codec.PDU, size int) codec.PDU {
+	w := codec.NewWriter()
+	p.Marshal(w)
+	require.Equal(t, size, w.Len())
+
+	p1, err := Parse(bytes.NewReader(w.Bytes()), V30, logger.With())
+	require.NoError(t, err)
+	return p1
+}
+
+func TestForwardReq(t *testing.T) {
+	p := NewForwardReq(V30).(*ForwardReq)
+	p.DestSMGWNo = "010001"
+	p.SrcSMGWNo = "020001"
+	p.SmcNo = "000001"
+	p.MsgId = "0200011018"
+	p.MsgType = 6
+	p.NeedReport = 1
+	p.Priority = 3
+	p.ServiceID = "test"
+	p.FeeType = "01"
+	p.FeeCode = "000000"
+	p.MsgFormat = 15
+	p.SrcTermID = "10690001"
+	p.DestTermID = "13300000001"
+	p.ChargeTermID = "13300000001"
+	p.Message.SetMessage("你好", codec.GB18030)
+	p.RegisterOptionalParam(codec.NewTlv(codec.Tag(LinkID), []byte("link")))
+
+	p1 := roundTrip(t, p, 12+153+1+4+8+2+2+4).(*ForwardReq)
+	require.Equal(t, "你好", p1.Message.GetMessage())
+	require.Equal(t, []byte("link"), p1.OptionalParameters[codec.Tag(LinkID)].Data)
+	p.Message, p1.Message = codec.ShortMessage{}, codec.ShortMessage{}
+	require.Equal(t, p, p1)
+
+	resp := p.GetResponse().(*ForwardResp)
+	resp.Status = 39
+	require.Equal(t, p.SequenceNumber, resp.SequenceNumber)
+	require.Equal(t, resp, roundTrip(t, resp, 26))
+}
+
+func TestQueryReq(t *testing.T) {
+	p := NewQueryReq(V30).(*QueryReq)
+	p.Time = "20261018"
+	p.QueryType = 1
+	p.QueryCode = "test"
+	require.Equal(t, p, roundTrip(t, p, 31))
+
+	resp := p.GetResponse().(*QueryResp)
+	resp.MtTlMsg, resp.MtTlUsr, resp.MtScs, resp.MtWt, resp.MtFl = 10, 8, 7, 2, 1
+	resp.MoScs, resp.MoWt, resp.MoFl = 5, 0, 1
+	require.Equal(t, p.QueryCode, resp.QueryCode)
+	require.Equal(t, resp, roundTrip(t, resp, 71))
+}
+
+func TestRouteUpdateReq(t *testing.T) {
+	mt := NewMTRouteUpdateReq(V30).(*MTRouteUpdateReq)
+	mt.UpdateType = ROUTE_UPDATE
+	mt.RouteId = 1001
+	mt.DestGatewayID = "010001"
+	mt.DestGatewayIP = "192.168.100.100"
+	mt.DestGatewayPort = 8890
+	mt.StartTermID = "133000"
+	mt.EndTermID = "133999"
+	mt.ProvinceCode = "010"
+	mt.UserType = 1
+	require.Equal(t, mt, roundTrip(t, mt, 12+53))
+
+	mtResp := mt.GetResponse().(*MTRouteUpdateResp)
+	mtResp.Status = 71
+	require.Equal(t, mtResp, roundTrip(t, mtResp, 16))
+
+	mo := NewMORouteUpdateReq(V30).(*MORouteUpdateReq)
+	mo.UpdateType = ROUTE_ADD
+	mo.RouteId = 1002
+	mo.DestGatewayID = "010001"
+	mo.DestGatewayIP = "10.0.0.1"
+	mo.DestGatewayPort = 8890
+	mo.SPID = "10001"
+	mo.SPCode = "1069000"
+	mo.SPAccessType = 1
+	require.Equal(t, mo, roundTrip(t, mo, 12+66))
+
+	moResp := mo.GetResponse().(*MORouteUpdateResp)
+	require.Equal(t, moResp, roundTrip(t, moResp, 16))
+}
+
+func TestDeliverLongMessage(t *testing.T) {
+	parts, err := codec.NewLongMessageWithEncoding(strings.Repeat("上行长短信", 20), codec.UCS2)
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+
+	for i, sm := range parts {
+		p := NewDeliverReq(V30).(*DeliverReq)
+		p.MsgId = "0200011018"
+		p.MsgFormat = sm.DataCoding()
+		p.SrcTermID = "13300000001"
+		p.DestTermID = "10690001"
+		p.Message = *sm
+		p.SetPkTotal(2)
+		p.SetPkNumber(byte(i + 1))
+
+		p1 := roundTrip(t, p, 12+68+1+sm.MsgLength()+8+5+5+5).(*DeliverReq)
+		require.True(t, p1.TpUdhi())
+		total, num, _, ok := p1.Message.UDHeader().GetConcatRef()
+		require.True(t, ok)
+		require.Equal(t, byte(2), total)
+		require.Equal(t, byte(i+1), num)
+		require.Equal(t, sm.GetMessage(), p1.Message.GetMessage())
+		v, ok := p1.PkTotal()
+		require.True(t, ok)
+		require.Equal(t, byte(2), v)
+		v, ok = p1.PkNumber()
+		require.True(t, ok)
+		require.Equal(t, byte(i+1), v)
+	}
+}
+
+func TestSubmitTlv(t *testing.T) {
+	p := NewSubmitReq(V30).(*SubmitReq)
+	p.DestTermID = []string{"13300000001"}
+	p.Message.SetMessage("hi", codec.ASCII)
+	p.SetTpPid(1)
+	p.SetChargeUserType(2)
+	p.SetSubmitMsgType(13)
+	require.NoError(t, p.SetLinkID("link-0001"))
+	require.NoError(t, p.SetMsgSrc("10001"))
+	require.NoError(t, p.SetMServiceID("mservice"))
+	require.NoError(t, p.SetDestTermPseudo("pseudo"))
+	require.Error(t, p.SetLinkID(strings.Repeat("x", 21)))
+	require.Error(t, p.SetMsgSrc("123456789"))
+
+	p1 := roundTrip(t, p, 12+105+21+3+8+3*5+24+12+25+10).(*SubmitReq)
+	require.False(t, p1.TpUdhi())
+	v, ok := p1.TpPid()
+	require.True(t, ok)
+	require.Equal(t, byte(1), v)
+	v, _ = p1.ChargeUserType()
+	require.Equal(t, byte(2), v)
+	v, _ = p1.SubmitMsgType()
+	require.Equal(t, byte(13), v)
+	_, ok = p1.PkTotal()
+	require.False(t, ok)
+	require.Equal(t, "link-0001", p1.LinkID())
+	require.Len(t, p1.OptionalParameters[codec.Tag(LinkID)].Data, 20)
+	require.Equal(t, "10001", p1.MsgSrc())
+	require.Equal(t, "mservice", p1.MServiceID())
+	require.Equal(t, "pseudo", p1.DestTermPseudo())
+	require.Equal(t, "", p1.ChargeTermPseudo())
+
+	// 长度不符的参数视为不存在
+	p1.RegisterOptionalParam(codec.NewTlv(codec.Tag(PkTotal), []byte{0, 2}))
+	_, ok = p1.PkTotal()
+	require.False(t, ok)
+}
+
+func TestInvalidTlv(t *testing.T) {
+	p := NewSubmitReq(V30).(*SubmitReq)
+	p.DestTermID = []string{"13300000001"}
+	p.Message.SetMessage("hi", codec.ASCII)
+	p.RegisterOptionalParam(codec.NewTlv(codec.Tag(PkTotal), []byte{0, 2}))
+	p.RegisterOptionalParam(codec.NewTlv(codec.Tag(0x1234), []byte{0, 2}))
+	w := codec.NewWriter()
+	p.Marshal(w)
+
+	// 长度不符时返回 ErrInvalidTlv, 报文头可用于回复, 未登记的 tag 不校验
+	p1, err := Parse(bytes.NewReader(w.Bytes()), V30, logger.With())
+	require.ErrorIs(t, err, smserror.ErrInvalidTlv)
+	require.Equal(t, p.SequenceNumber, p1.GetSequenceNumber())
+	_, ok := p1.(*SubmitReq).PkTotal()
+	require.False(t, ok)
+}
+
+func TestParseHeaderOnly(t *testing.T) {
+	// 包头 12 字节, 只有包头的连续帧逐个解析, 不能多读下一帧
+	var buf bytes.Buffer
+	for _, seq := range []int32{1, 2} {
+		p := NewActiveTestReq(V30)
+		p.SetSequenceNumber(seq)
+		w := codec.NewWriter()
+		p.Marshal(w)
+		require.Equal(t, PDU_HEADER_SIZE, w.Len())
+		buf.Write(w.Bytes())
+	}
+	for _, seq := range []int32{1, 2} {
+		p, err := Parse(&buf, V30, logger.With())
+		require.NoError(t, err)
+		require.IsType(t, &ActiveTestReq{}, p)
+		require.Equal(t, seq, p.GetSequenceNumber())
+	}
+	require.Zero(t, buf.Len())
+}
+
+func TestLoginReqKeepAuthenticator(t *testing.T) {
+	p := NewLoginReq(V30).(*LoginReq)
+	p.ClientID = "900001"
+	p.Secret = "123456"
+	p.Timestamp = 1018082800
+	w := codec.NewWriter()
+	p.Marshal(w)
+
+	// 解析所得无密钥, 重新编码保持原认证码
+	p1, err := Parse(bytes.NewReader(w.Bytes()), V30, logger.With())
+	require.NoError(t, err)
+	req := p1.(*LoginReq)
+	require.Equal(t, p.AuthenticatorClient, req.AuthenticatorClient)
+	w1 := codec.NewWriter()
+	req.Marshal(w1)
+	require.Equal(t, w.Bytes(), w1.Bytes())
+}
@@ -0,0 +1,111 @@
+package smgp
+
+import (
+	"github.com/zhiyin2021/zysms/codec"
+)
+
+// QueryReq SP 查询某天的短消息统计
+type QueryReq struct {
+	base
+	Time      string // 【8字节】查询日期 YYYYMMDD
+	QueryType byte   // 【1字节】0: 总数查询 1: 按业务类型查询
+	QueryCode string // 【10字节】业务代码, QueryType 为 0 时无效
+}
+
+type QueryResp struct {
+	base
+	Time      string // 【8字节】查询日期 YYYYMMDD
+	QueryType byte   // 【1字节】查询类别
+	QueryCode string // 【10字节】业务代码
+	MtTlMsg   uint32 // 【4字节】从 SP 接收的消息总数
+	MtTlUsr   uint32 // 【4字节】从 SP 接收的用户总数
+	MtScs     uint32 // 【4字节】成功转发数量
+	MtWt      uint32 // 【4字节】待转发数量
+	MtFl      uint32 // 【4字节】转发失败数量
+	MoScs     uint32 // 【4字节】向 SP 成功送达数量
+	MoWt      uint32 // 【4字节】向 SP 待送达数量
+	MoFl      uint32 // 【4字节】向 SP 送达失败数量
+	Reserve   string // 【8字节】保留
+}
+
+func NewQueryReq(ver codec.Version) codec.PDU {
+	return &QueryReq{
+		base: newBase(ver, SMGP_QUERY, 0),
+	}
+}
+func NewQueryResp(ver codec.Version) codec.PDU {
+	return &QueryResp{
+		base: newBase(ver, SMGP_QUERY_RESP, 0),
+	}
+}
+
+// Pack packs the QueryReq to bytes stream.
+func (p *QueryReq) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteStr(p.Time, 8)
+		bw.WriteByte(p.QueryType)
+		bw.WriteStr(p.QueryCode, 10)
+	})
+}
+
+// Unpack unpack the binary byte stream to a QueryReq variable.
+func (p *QueryReq) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.Time = br.ReadStr(8)
+		p.QueryType = br.ReadU8()
+		p.QueryCode = br.ReadStr(10)
+		return br.Err()
+	})
+}
+
+// GetResponse implements PDU interface.
+func (b *QueryReq) GetResponse() codec.PDU {
+	return &QueryResp{
+		base:      newBase(b.Version, SMGP_QUERY_RESP, b.SequenceNumber),
+		Time:      b.Time,
+		QueryType: b.QueryType,
+		QueryCode: b.QueryCode,
+	}
+}
+
+// Pack packs the QueryResp to bytes stream.
+func (p *QueryResp) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteStr(p.Time, 8)
+		bw.WriteByte(p.QueryType)
+		bw.WriteStr(p.QueryCode, 10)
+		bw.WriteU32(p.MtTlMsg)
+		bw.WriteU32(p.MtTlUsr)
+		bw.WriteU32(p.MtScs)
+		bw.WriteU32(p.MtWt)
+		bw.WriteU32(p.MtFl)
+		bw.WriteU32(p.MoScs)
+		bw.WriteU32(p.MoWt)
+		bw.WriteU32(p.MoFl)
+		bw.WriteStr(p.Reserve, 8)
+	})
+}
+
+// Unpack unpack the binary byte stream to a QueryResp variable.
+func (p *QueryResp) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.Time = br.ReadStr(8)
+		p.QueryType = br.ReadU8()
+		p.QueryCode = br.ReadStr(10)
+		p.MtTlMsg = br.ReadU32()
+		p.MtTlUsr = br.ReadU32()
+		p.MtScs = br.ReadU32()
+		p.MtWt = br.ReadU32()
+		p.MtFl = br.ReadU32()
+		p.MoScs = br.ReadU32()
+		p.MoWt = br.ReadU32()
+		p.MoFl = br.ReadU32()
+		p.Reserve = br.ReadStr(8)
+		return br.Err()
+	})
+}
+
+// GetResponse implements PDU interface.
+func (b *QueryResp) GetResponse() codec.PDU {
+	return nil
+}
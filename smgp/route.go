@@ -0,0 +1,190 @@
+package smgp
+
+import (
+	"github.com/zhiyin2021/zysms/codec"
+)
+
+// 路由更新类型
+const (
+	ROUTE_ADD    byte = 0
+	ROUTE_DELETE byte = 1
+	ROUTE_UPDATE byte = 2
+)
+
+// MTRouteUpdateReq 更新下行(按号码段)路由
+type MTRouteUpdateReq struct {
+	base
+	UpdateType      byte   // 【1字节】0: 增加 1: 删除 2: 更新
+	RouteId         uint32 // 【4字节】路由编号
+	DestGatewayID   string // 【6字节】目标网关代码
+	DestGatewayIP   string // 【15字节】目标网关IP
+	DestGatewayPort uint16 // 【2字节】目标网关端口
+	StartTermID     string // 【6字节】号码段起始, 手机号码前 6 位或 7 位
+	EndTermID       string // 【6字节】号码段结束
+	ProvinceCode    string // 【4字节】号码段所属省代码
+	UserType        byte   // 【1字节】用户类型
+	Reserve         string // 【8字节】保留
+}
+
+type MTRouteUpdateResp struct {
+	base
+	Status Status
+}
+
+// MORouteUpdateReq 更新上行(按 SP 接入号)路由
+type MORouteUpdateReq struct {
+	base
+	UpdateType      byte   // 【1字节】0: 增加 1: 删除 2: 更新
+	RouteId         uint32 // 【4字节】路由编号
+	DestGatewayID   string // 【6字节】目标网关代码
+	DestGatewayIP   string // 【15字节】目标网关IP
+	DestGatewayPort uint16 // 【2字节】目标网关端口
+	SPID            string // 【8字节】SP 企业代码
+	SPCode          string // 【21字节】SP 服务代码(接入号)
+	SPAccessType    byte   // 【1字节】SP 接入类型
+	Reserve         string // 【8字节】保留
+}
+
+type MORouteUpdateResp struct {
+	base
+	Status Status
+}
+
+func NewMTRouteUpdateReq(ver codec.Version) codec.PDU {
+	return &MTRouteUpdateReq{
+		base: newBase(ver, SMGP_MT_ROUTE_UPDATE, 0),
+	}
+}
+func NewMTRouteUpdateResp(ver codec.Version) codec.PDU {
+	return &MTRouteUpdateResp{
+		base: newBase(ver, SMGP_MT_ROUTE_UPDATE_RESP, 0),
+	}
+}
+func NewMORouteUpdateReq(ver codec.Version) codec.PDU {
+	return &MORouteUpdateReq{
+		base: newBase(ver, SMGP_MO_ROUTE_UPDATE, 0),
+	}
+}
+func NewMORouteUpdateResp(ver codec.Version) codec.PDU {
+	return &MORouteUpdateResp{
+		base: newBase(ver, SMGP_MO_ROUTE_UPDATE_RESP, 0),
+	}
+}
+
+// Pack packs the MTRouteUpdateReq to bytes stream.
+func (p *MTRouteUpdateReq) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteByte(p.UpdateType)
+		bw.WriteU32(p.RouteId)
+		bw.WriteStr(p.DestGatewayID, 6)
+		bw.WriteStr(p.DestGatewayIP, 15)
+		bw.WriteU16(p.DestGatewayPort)
+		bw.WriteStr(p.StartTermID, 6)
+		bw.WriteStr(p.EndTermID, 6)
+		bw.WriteStr(p.ProvinceCode, 4)
+		bw.WriteByte(p.UserType)
+		bw.WriteStr(p.Reserve, 8)
+	})
+}
+
+// Unpack unpack the binary byte stream to a MTRouteUpdateReq variable.
+func (p *MTRouteUpdateReq) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.UpdateType = br.ReadU8()
+		p.RouteId = br.ReadU32()
+		p.DestGatewayID = br.ReadStr(6)
+		p.DestGatewayIP = br.ReadStr(15)
+		p.DestGatewayPort = br.ReadU16()
+		p.StartTermID = br.ReadStr(6)
+		p.EndTermID = br.ReadStr(6)
+		p.ProvinceCode = br.ReadStr(4)
+		p.UserType = br.ReadU8()
+		p.Reserve = br.ReadStr(8)
+		return br.Err()
+	})
+}
+
+// GetResponse implements PDU interface.
+func (b *MTRouteUpdateReq) GetResponse() codec.PDU {
+	return &MTRouteUpdateResp{
+		base: newBase(b.Version, SMGP_MT_ROUTE_UPDATE_RESP, b.SequenceNumber),
+	}
+}
+
+// Pack packs the MTRouteUpdateResp to bytes stream.
+func (p *MTRouteUpdateResp) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteU32(uint32(p.Status))
+	})
+}
+
+// Unpack unpack the binary byte stream to a MTRouteUpdateResp variable.
+func (p *MTRouteUpdateResp) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.Status = Status(br.ReadU32())
+		return br.Err()
+	})
+}
+
+// GetResponse implements PDU interface.
+func (b *MTRouteUpdateResp) GetResponse() codec.PDU {
+	return nil
+}
+
+// Pack packs the MORouteUpdateReq to bytes stream.
+func (p *MORouteUpdateReq) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteByte(p.UpdateType)
+		bw.WriteU32(p.RouteId)
+		bw.WriteStr(p.DestGatewayID, 6)
+		bw.WriteStr(p.DestGatewayIP, 15)
+		bw.WriteU16(p.DestGatewayPort)
+		bw.WriteStr(p.SPID, 8)
+		bw.WriteStr(p.SPCode, 21)
+		bw.WriteByte(p.SPAccessType)
+		bw.WriteStr(p.Reserve, 8)
+	})
+}
+
+// Unpack unpack the binary byte stream to a MORouteUpdateReq variable.
+func (p *MORouteUpdateReq) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.UpdateType = br.ReadU8()
+		p.RouteId = br.ReadU32()
+		p.DestGatewayID = br.ReadStr(6)
+		p.DestGatewayIP = br.ReadStr(15)
+		p.DestGatewayPort = br.ReadU16()
+		p.SPID = br.ReadStr(8)
+		p.SPCode = br.ReadStr(21)
+		p.SPAccessType = br.ReadU8()
+		p.Reserve = br.ReadStr(8)
+		return br.Err()
+	})
+}
+
+// GetResponse implements PDU interface.
+func (b *MORouteUpdateReq) GetResponse() codec.PDU {
+	return &MORouteUpdateResp{
+		base: newBase(b.Version, SMGP_MO_ROUTE_UPDATE_RESP, b.SequenceNumber),
+	}
+}
+
+// Pack packs the MORouteUpdateResp to bytes stream.
+func (p *MORouteUpdateResp) Marshal(w *codec.BytesWriter) {
+	p.base.marshal(w, func(bw *codec.BytesWriter) {
+		bw.WriteU32(uint32(p.Status))
+	})
+}
+
+// Unpack unpack the binary byte stream to a MORouteUpdateResp variable.
+func (p *MORouteUpdateResp) Unmarshal(w *codec.BytesReader) error {
+	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+		p.Status = Status(br.ReadU32())
+		return br.Err()
+	})
+}
+
+// GetResponse implements PDU interface.
+func (b *MORouteUpdateResp) GetResponse() codec.PDU {
+	return nil
+}
@@ -45,8 +45,8 @@ func NewSubmitResp(ver codec.Version) codec.PDU {
 
 // Pack packs the ActiveTestReq to bytes stream for client side.
 func (p *SubmitReq) Marshal(w *codec.BytesWriter) {
-	if p.Message.IsLongMessage() {
-		p.RegisterOptionalParam(codec.NewTlv(codec.TagTPUdhi, []byte{0x01}))
+	if hasUDH(&p.Message) {
+		p.SetTpUdhi(true)
 	}
 	p.base.marshal(w, func(bw *codec.BytesWriter) {
 		bw.WriteByte(p.SubType)
@@ -74,8 +74,9 @@ func (p *SubmitReq) Marshal(w *codec.BytesWriter) {
 // Unpack unpack the binary byte stream to a ActiveTestReq variable.
 // After unpack, you will get all value of fields in
 // ActiveTestReq struct.
-func (p *SubmitReq) Unmarshal(w *codec.BytesReader) error {
-	return p.base.unmarshal(w, func(br *codec.BytesReader) error {
+func (p *SubmitReq) Unmarshal(w *codec.BytesReader) (err error) {
+	var msg []byte
+	err = p.base.unmarshal(w, func(br *codec.BytesReader) error {
 		p.SubType = br.ReadU8()
 		p.NeedReport = br.ReadU8()
 		p.Priority = br.ReadU8()
@@ -97,17 +98,14 @@ func (p *SubmitReq) Unmarshal(w *codec.BytesReader) error {
 		// 0009   0001   04     #   pkTotal
 		// 000a   0001   01     #   pkNumber
 
-		p.Message.Unmarshal(br, p.TpUdhi(), p.MsgFormat)
+		msg = readMessage(br)
 		p.Reserve = br.ReadStr(8)
 		return br.Err()
 	})
-}
-func (p *SubmitReq) TpUdhi() bool {
-	udhi := byte(0)
-	if tag, ok := p.OptionalParameters[codec.TagTPUdhi]; ok && len(tag.Data) > 0 {
-		udhi = tag.Data[0]
+	if err == nil {
+		err = p.decodeMessage(&p.Message, msg, p.MsgFormat)
 	}
-	return udhi == 1
+	return err
 }
 
 // GetResponse implements PDU interface.
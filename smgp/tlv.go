@@ -0,0 +1,160 @@
+package smgp
+
+import (
+	"bytes"
+
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+// TlvSpecs smgp 3.0 可选参数的类型及长度
+var TlvSpecs = map[codec.Tag]codec.TlvSpec{
+	codec.Tag(TP_pid):           {Name: "TP_pid", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(TP_udhi):          {Name: "TP_udhi", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(LinkID):           {Name: "LinkID", Type: codec.TlvOctet, Min: 1, Max: 20},
+	codec.Tag(ChargeUserType):   {Name: "ChargeUserType", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(ChargeTermType):   {Name: "ChargeTermType", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(ChargeTermPseudo): {Name: "ChargeTermPseudo", Type: codec.TlvOctet, Min: 1, Max: 255},
+	codec.Tag(DestTermType):     {Name: "DestTermType", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(DestTermPseudo):   {Name: "DestTermPseudo", Type: codec.TlvOctet, Min: 1, Max: 255},
+	codec.Tag(PkTotal):          {Name: "PkTotal", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(PkNumber):         {Name: "PkNumber", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(SubmitMsgType):    {Name: "SubmitMsgType", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(SPDealReslt):      {Name: "SPDealReslt", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(SrcTermType):      {Name: "SrcTermType", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(SrcTermPseudo):    {Name: "SrcTermPseudo", Type: codec.TlvOctet, Min: 1, Max: 255},
+	codec.Tag(NodesCount):       {Name: "NodesCount", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(MsgSrc):           {Name: "MsgSrc", Type: codec.TlvOctet, Min: 1, Max: 8},
+	codec.Tag(SrcType):          {Name: "SrcType", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.Tag(MServiceID):       {Name: "MServiceID", Type: codec.TlvOctet, Min: 1, Max: 21},
+}
+
+// ValidateTlv 按 TlvSpecs 校验长度
+func ValidateTlv(f codec.Field) error {
+	if spec, ok := TlvSpecs[f.Tag]; ok {
+		return spec.Check(f)
+	}
+	return nil
+}
+
+// Submit 可选参数, 整数类型均为 1 字节, 不存在或长度不符时 ok 为 false
+func (p *SubmitReq) TpPid() (byte, bool)          { return p.tlvByte(TP_pid) }
+func (p *SubmitReq) SetTpPid(v byte)              { p.setTlvByte(TP_pid, v) }
+func (p *SubmitReq) TpUdhi() bool                 { return p.tpUdhi() }
+func (p *SubmitReq) SetTpUdhi(v bool)             { p.setTpUdhi(v) }
+func (p *SubmitReq) ChargeUserType() (byte, bool) { return p.tlvByte(ChargeUserType) }
+func (p *SubmitReq) SetChargeUserType(v byte)     { p.setTlvByte(ChargeUserType, v) }
+func (p *SubmitReq) ChargeTermType() (byte, bool) { return p.tlvByte(ChargeTermType) }
+func (p *SubmitReq) SetChargeTermType(v byte)     { p.setTlvByte(ChargeTermType, v) }
+func (p *SubmitReq) DestTermType() (byte, bool)   { return p.tlvByte(DestTermType) }
+func (p *SubmitReq) SetDestTermType(v byte)       { p.setTlvByte(DestTermType, v) }
+func (p *SubmitReq) PkTotal() (byte, bool)        { return p.tlvByte(PkTotal) }
+func (p *SubmitReq) SetPkTotal(v byte)            { p.setTlvByte(PkTotal, v) }
+func (p *SubmitReq) PkNumber() (byte, bool)       { return p.tlvByte(PkNumber) }
+func (p *SubmitReq) SetPkNumber(v byte)           { p.setTlvByte(PkNumber, v) }
+func (p *SubmitReq) SubmitMsgType() (byte, bool)  { return p.tlvByte(SubmitMsgType) }
+func (p *SubmitReq) SetSubmitMsgType(v byte)      { p.setTlvByte(SubmitMsgType, v) }
+func (p *SubmitReq) SPDealReslt() (byte, bool)    { return p.tlvByte(SPDealReslt) }
+func (p *SubmitReq) SetSPDealReslt(v byte)        { p.setTlvByte(SPDealReslt, v) }
+
+// 定长字符串, LinkID【20字节】MsgSrc【8字节】MServiceID【21字节】, 超长时返回错误; 伪码为变长
+func (p *SubmitReq) LinkID() string                     { return p.tlvStr(LinkID) }
+func (p *SubmitReq) SetLinkID(v string) error           { return p.setTlvStr(LinkID, v, 20) }
+func (p *SubmitReq) MsgSrc() string                     { return p.tlvStr(MsgSrc) }
+func (p *SubmitReq) SetMsgSrc(v string) error           { return p.setTlvStr(MsgSrc, v, 8) }
+func (p *SubmitReq) MServiceID() string                 { return p.tlvStr(MServiceID) }
+func (p *SubmitReq) SetMServiceID(v string) error       { return p.setTlvStr(MServiceID, v, 21) }
+func (p *SubmitReq) ChargeTermPseudo() string           { return p.tlvStr(ChargeTermPseudo) }
+func (p *SubmitReq) SetChargeTermPseudo(v string) error { return p.setTlvStr(ChargeTermPseudo, v, 0) }
+func (p *SubmitReq) DestTermPseudo() string             { return p.tlvStr(DestTermPseudo) }
+func (p *SubmitReq) SetDestTermPseudo(v string) error   { return p.setTlvStr(DestTermPseudo, v, 0) }
+
+// Deliver 可选参数, PkTotal/PkNumber 用于上行长短信
+func (p *DeliverReq) TpPid() (byte, bool)             { return p.tlvByte(TP_pid) }
+func (p *DeliverReq) SetTpPid(v byte)                 { p.setTlvByte(TP_pid, v) }
+func (p *DeliverReq) TpUdhi() bool                    { return p.tpUdhi() }
+func (p *DeliverReq) SetTpUdhi(v bool)                { p.setTpUdhi(v) }
+func (p *DeliverReq) SrcTermType() (byte, bool)       { return p.tlvByte(SrcTermType) }
+func (p *DeliverReq) SetSrcTermType(v byte)           { p.setTlvByte(SrcTermType, v) }
+func (p *DeliverReq) PkTotal() (byte, bool)           { return p.tlvByte(PkTotal) }
+func (p *DeliverReq) SetPkTotal(v byte)               { p.setTlvByte(PkTotal, v) }
+func (p *DeliverReq) PkNumber() (byte, bool)          { return p.tlvByte(PkNumber) }
+func (p *DeliverReq) SetPkNumber(v byte)              { p.setTlvByte(PkNumber, v) }
+func (p *DeliverReq) SubmitMsgType() (byte, bool)     { return p.tlvByte(SubmitMsgType) }
+func (p *DeliverReq) SetSubmitMsgType(v byte)         { p.setTlvByte(SubmitMsgType, v) }
+func (p *DeliverReq) SPDealReslt() (byte, bool)       { return p.tlvByte(SPDealReslt) }
+func (p *DeliverReq) SetSPDealReslt(v byte)           { p.setTlvByte(SPDealReslt, v) }
+func (p *DeliverReq) LinkID() string                  { return p.tlvStr(LinkID) }
+func (p *DeliverReq) SetLinkID(v string) error        { return p.setTlvStr(LinkID, v, 20) }
+func (p *DeliverReq) SrcTermPseudo() string           { return p.tlvStr(SrcTermPseudo) }
+func (p *DeliverReq) SetSrcTermPseudo(v string) error { return p.setTlvStr(SrcTermPseudo, v, 0) }
+
+// tpUdhi 消息内容是否包含 UDH
+func (c *base) tpUdhi() bool {
+	v, ok := c.tlvByte(TP_udhi)
+	return ok && v == 1
+}
+
+// setTpUdhi 有 UDH 时 Marshal 自动设置
+func (c *base) setTpUdhi(v bool) {
+	if v {
+		c.setTlvByte(TP_udhi, 1)
+	} else {
+		c.setTlvByte(TP_udhi, 0)
+	}
+}
+
+// tlvByte 不存在或长度不符时 ok 为 false
+func (c *base) tlvByte(tag uint16) (byte, bool) {
+	f, ok := c.OptionalParameters[codec.Tag(tag)]
+	if !ok || len(f.Data) != 1 {
+		return 0, false
+	}
+	return f.Data[0], true
+}
+
+func (c *base) setTlvByte(tag uint16, v byte) {
+	c.RegisterOptionalParam(codec.NewTlv(codec.Tag(tag), []byte{v}))
+}
+
+// tlvStr 不存在或长度不符时返回空
+func (c *base) tlvStr(tag uint16) string {
+	f, ok := c.OptionalParameters[codec.Tag(tag)]
+	if !ok || ValidateTlv(f) != nil {
+		return ""
+	}
+	return string(bytes.TrimRight(f.Data, "\x00"))
+}
+
+// setTlvStr 定长参数补 0, size 为 0 表示变长, 空字符串删除该参数
+func (c *base) setTlvStr(tag uint16, v string, size int) error {
+	if size > 0 && len(v) > size || len(v) > 255 {
+		return smserror.ErrMethodParamsInvalid
+	}
+	if v == "" {
+		delete(c.OptionalParameters, codec.Tag(tag))
+		return nil
+	}
+	data := []byte(v)
+	if size > 0 {
+		data = append(data, make([]byte, size-len(v))...)
+	}
+	c.RegisterOptionalParam(codec.NewTlv(codec.Tag(tag), data))
+	return nil
+}
+
+// readMessage 读取 MsgLength 及 MsgContent, 标识 UDH 的 TP_udhi 在其后的 TLV 中, 读完 TLV 再解析
+func readMessage(br *codec.BytesReader) []byte {
+	n := br.ReadU8()
+	return append([]byte{n}, br.ReadN(int(n))...)
+}
+
+// decodeMessage 按 TP_udhi 拆分 UDH
+func (c *base) decodeMessage(msg *codec.ShortMessage, data []byte, enc byte) error {
+	return msg.Unmarshal(codec.NewReader(data), c.tpUdhi(), enc)
+}
+
+// hasUDH 消息带 UDH 时需要设置 TP_udhi
+func hasUDH(msg *codec.ShortMessage) bool {
+	return len(msg.UDHeader()) > 0 || msg.IsLongMessage()
+}
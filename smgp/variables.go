@@ -53,8 +53,11 @@ const (
 	SMGP_SUBMIT, SMGP_SUBMIT_RESP
 	SMGP_DELIVER, SMGP_DELIVER_RESP
 	SMGP_ACTIVE_TEST, SMGP_ACTIVE_TEST_RESP
-	_, _
+	SMGP_FORWARD, SMGP_FORWARD_RESP
 	SMGP_EXIT, SMGP_EXIT_RESP
+	SMGP_QUERY, SMGP_QUERY_RESP
+	SMGP_MT_ROUTE_UPDATE, SMGP_MT_ROUTE_UPDATE_RESP
+	SMGP_MO_ROUTE_UPDATE, SMGP_MO_ROUTE_UPDATE_RESP
 	SMGP_REQUEST_MAX, SMGP_RESPONSE_MAX
 )
 
@@ -141,6 +144,22 @@ func CreatePDUHeader(header Header, ver codec.Version) (codec.PDU, error) {
 		return &ExitReq{base: base}, nil
 	case SMGP_EXIT_RESP:
 		return &ExitResp{base: base}, nil
+	case SMGP_FORWARD:
+		return &ForwardReq{base: base}, nil
+	case SMGP_FORWARD_RESP:
+		return &ForwardResp{base: base}, nil
+	case SMGP_QUERY:
+		return &QueryReq{base: base}, nil
+	case SMGP_QUERY_RESP:
+		return &QueryResp{base: base}, nil
+	case SMGP_MT_ROUTE_UPDATE:
+		return &MTRouteUpdateReq{base: base}, nil
+	case SMGP_MT_ROUTE_UPDATE_RESP:
+		return &MTRouteUpdateResp{base: base}, nil
+	case SMGP_MO_ROUTE_UPDATE:
+		return &MORouteUpdateReq{base: base}, nil
+	case SMGP_MO_ROUTE_UPDATE_RESP:
+		return &MORouteUpdateResp{base: base}, nil
 	default:
 		return nil, smserror.ErrUnknownCommandID
 	}
@@ -0,0 +1,102 @@
+package zysms
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smgp"
+	"go.uber.org/zap"
+)
+
+func TestSmgpUnsupported(t *testing.T) {
+	_, l := testListen(t, codec.SMGP30)
+	_, c := testDial(t, codec.SMGP30, l, nil)
+
+	query := smgp.NewQueryReq(smgp.V30).(*smgp.QueryReq)
+	query.Time = "20260101"
+	tests := []struct {
+		req   codec.PDU
+		check func(t *testing.T, resp codec.PDU)
+	}{
+		{smgp.NewMTRouteUpdateReq(smgp.V30), func(t *testing.T, resp codec.PDU) {
+			require.Equal(t, smgp.Status(68), resp.(*smgp.MTRouteUpdateResp).Status)
+		}},
+		{smgp.NewMORouteUpdateReq(smgp.V30), func(t *testing.T, resp codec.PDU) {
+			require.Equal(t, smgp.Status(68), resp.(*smgp.MORouteUpdateResp).Status)
+		}},
+		{smgp.NewForwardReq(smgp.V30), func(t *testing.T, resp codec.PDU) {
+			require.Equal(t, smgp.Status(11), resp.(*smgp.ForwardResp).Status)
+		}},
+		{query, func(t *testing.T, resp codec.PDU) {
+			r := resp.(*smgp.QueryResp)
+			require.Equal(t, "20260101", r.Time)
+			require.Zero(t, r.MtTlMsg)
+		}},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		resp, err := c.Request(ctx, tt.req)
+		cancel()
+		require.NoError(t, err, "%T", tt.req)
+		tt.check(t, resp)
+	}
+}
+
+func TestSmgpRouteUpdateOnRecv(t *testing.T) {
+	s, l := testListen(t, codec.SMGP30)
+	s.OnRecv = func(c Conn, p PDU) {
+		if req, ok := p.(*smgp.MTRouteUpdateReq); ok {
+			c.SendPDU(req.GetResponse())
+		}
+	}
+	_, c := testDial(t, codec.SMGP30, l, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := c.Request(ctx, smgp.NewMTRouteUpdateReq(smgp.V30))
+	require.NoError(t, err)
+	require.Equal(t, smgp.Status(0), resp.(*smgp.MTRouteUpdateResp).Status)
+}
+
+func TestSmgpInvalidTlv(t *testing.T) {
+	s, l := testListen(t, codec.SMGP30)
+	s.OnRecv = func(c Conn, p PDU) {
+		t.Errorf("unexpected %T", p)
+	}
+	newReq := func() *smgp.SubmitReq {
+		p := smgp.NewSubmitReq(smgp.V30).(*smgp.SubmitReq)
+		p.DestTermID = []string{"13300000001"}
+		p.Message.SetMessage("hi", codec.ASCII)
+		p.RegisterOptionalParam(codec.NewTlv(codec.Tag(smgp.PkTotal), []byte{0, 2}))
+		return p
+	}
+
+	// 未登录时按状态拒绝并断开
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	req := newReq()
+	writePDU(t, conn, req)
+	resp, err := smgp.Parse(conn, smgp.V30, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	require.Equal(t, req.GetSequenceNumber(), resp.GetSequenceNumber())
+	require.Equal(t, smgp.Status(21), resp.(*smgp.SubmitResp).Status)
+	_, err = smgp.Parse(conn, smgp.V30, zap.NewNop().Sugar())
+	require.Error(t, err)
+
+	// 已登录时每个请求回复状态 10, 连接保持
+	_, c := testDial(t, codec.SMGP30, l, nil)
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		resp, err := c.Request(ctx, newReq())
+		cancel()
+		require.NoError(t, err)
+		require.Equal(t, smgp.Status(10), resp.(*smgp.SubmitResp).Status)
+	}
+	require.True(t, c.IsConnected())
+}
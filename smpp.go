@@ -1,6 +1,7 @@
 package zysms
 
 import (
+	"errors"
 	"fmt"
 	"sync/atomic"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/zhiyin2021/zysms/enum"
 	"github.com/zhiyin2021/zysms/smpp"
 	"github.com/zhiyin2021/zysms/smserror"
+	"github.com/zhiyin2021/zysms/utils"
 )
 
 type smpp_action struct {
@@ -21,7 +23,7 @@ func newSmpp(conn *sms_conn) *smpp_action {
 
 func (c *smpp_action) login(uid string, pwd string) error {
 	// Login to the server.
-	req := smpp.NewBindRequest(smpp.Transceiver)
+	req := smpp.NewBindRequest(smppBindingType(c.bindType))
 	req.SystemID = uid
 	req.Password = pwd
 	req.InterfaceVersion = c.Typ
@@ -46,19 +48,39 @@ func (c *smpp_action) login(uid string, pwd string) error {
 	return nil
 }
 
-func (c *smpp_action) logout() {
-	c.SendPDU(smpp.NewUnbind())
+func (c *smpp_action) logout() codec.PDU {
+	return smpp.NewUnbind()
 }
 
 // RecvAndUnpackPkt receives smpp byte stream, and unpack it to some smpp packet structure.
 func (c *smpp_action) recv() (codec.PDU, error) {
-	if c.Connected == enum.CONN_DISCONNECTED {
+	if atomic.LoadInt32(&c.Connected) == enum.CONN_DISCONNECTED {
 		return nil, smserror.ErrConnIsClosed
 	}
-	pdu, err := smpp.Parse(c.Conn, c.logger)
+	var pdu codec.PDU
+	var err error
+	for {
+		pdu, err = smpp.Parse(c.Conn, c.logger)
+		if !errors.Is(err, smserror.ErrInvalidTlv) || pdu == nil {
+			break
+		}
+		// 可选参数长度不符, 请求回复 ESME_RINVOPTPARAMVAL 后继续读取,
+		// 响应照常处理, 状态不允许的请求由 rejectByState 回复
+		c.logger.Warnf("recv %T: %v", pdu, err)
+		resp := pdu.GetResponse()
+		if resp == nil || c.checkBindState(pdu) != nil {
+			err = nil
+			break
+		}
+		if h, ok := resp.GetHeader().(*smpp.Header); ok {
+			h.CommandStatus = smpp.ESME_RINVOPTPARAMVAL
+		}
+		c.SendPDU(resp)
+	}
 	if err != nil {
 		return nil, err
 	}
+	c.releaseWindow(pdu)
 	switch p := pdu.(type) {
 	case *smpp.EnquireLink: // 当收到心跳请求,内部直接回复心跳,并递归继续获取数据
 		if c.autoActiveResp {
@@ -73,26 +95,176 @@ func (c *smpp_action) recv() (codec.PDU, error) {
 		if p.CommandStatus != smpp.ESME_ROK {
 			return nil, smserror.NewSmsErr(int(p.CommandStatus), "smpp.login.error")
 		}
+		// outbind 发起的 bind_receiver 在接收协程中完成登录
+		if c.BindState() == enum.BIND_OPEN {
+			c.IsAuth = true
+			c.setBindState(smppBindState(p.CommandID))
+		}
+		// 服务端支持的版本较低时降级
+		if v, ok := p.ScInterfaceVersion(); ok && v < c.Typ {
+			c.Typ = v
+		}
 	case *smpp.Unbind:
+		c.setBindState(enum.BIND_UNBINDING)
 		resp := p.GetResponse()
 		c.SendPDU(resp)
 		time.Sleep(100 * time.Millisecond)
 		return nil, smserror.ErrConnIsClosed
+	case *smpp.Outbind:
+		if err := c.answerOutbind(p); err != nil {
+			return nil, err
+		}
 	case *smpp.BindRequest:
-		switch p.InterfaceVersion {
-		case smpp.V33, smpp.V34:
-			// 服务端自适应版本
-			c.Typ = p.InterfaceVersion
-			fallthrough
-		case 0:
-			c.logger = c.logger.With("v", c.Protocol.String(), "v1", c.Typ)
-		default:
+		// 服务端自适应版本, 不高于本端协议版本
+		v, ok := p.NegotiateVersion(c.Protocol.Version())
+		if !ok {
 			return nil, fmt.Errorf("smpp version not support [ %d ]", p.InterfaceVersion)
 		}
+		c.Typ = v
+		c.logger = c.logger.With("v", c.Protocol.String(), "v1", c.Typ)
 	}
 	return pdu, nil
 }
 
+// answerOutbind 服务端 outbind 后以 bind_receiver 登录, 设置了 Authenticator 时先校验服务端账号
+func (c *smpp_action) answerOutbind(p *smpp.Outbind) error {
+	if c.outbind == nil || c.BindState() != enum.BIND_OPEN {
+		return nil
+	}
+	auth := c.authenticator
+	if auth == nil {
+		auth = c.parent.Authenticator
+	}
+	if auth != nil {
+		acc, err := auth.Lookup(c, p.SystemID)
+		if err != nil {
+			return err
+		}
+		if acc == nil || acc.Password != p.Password {
+			return smserror.NewSmsErr(int(smpp.ESME_RBINDFAIL), "smpp.outbind.error")
+		}
+	}
+	req := smpp.NewBindRequest(smpp.Receiver)
+	req.SystemID = c.outbind.ID
+	req.Password = c.outbind.Password
+	req.InterfaceVersion = c.Typ
+	if c.systemType != "" {
+		req.SystemType = c.systemType
+	}
+	c.account = c.outbind.ID
+	return c.SendPDU(req)
+}
+
+func smppBindingType(state int32) smpp.BindingType {
+	switch state {
+	case enum.BIND_TX:
+		return smpp.Transmitter
+	case enum.BIND_RX:
+		return smpp.Receiver
+	}
+	return smpp.Transceiver
+}
+
+// verify 校验 SystemID/Password
+func (c *smpp_action) verify(pdu codec.PDU, auth Authenticator) (codec.PDU, string, error) {
+	req, ok := pdu.(*smpp.BindRequest)
+	if !ok {
+		return nil, "", nil
+	}
+	resp := req.GetResponse().(*smpp.BindResp)
+	fail := func(status codec.CommandStatus) (codec.PDU, string, error) {
+		resp.CommandStatus = status
+		return resp, "", smserror.NewSmsErr(int(status), "smpp.login.error")
+	}
+	if c.IsAuth {
+		return fail(smpp.ESME_RALYBND)
+	}
+	acc, err := auth.Lookup(c, req.SystemID)
+	if err != nil {
+		resp.CommandStatus = smpp.ESME_RBINDFAIL
+		return resp, "", err
+	}
+	if acc == nil {
+		return fail(smpp.ESME_RINVSYSID)
+	}
+	if acc.Password != req.Password {
+		return fail(smpp.ESME_RINVPASWD)
+	}
+	if !c.certAllowed(req.SystemID) {
+		return fail(smpp.ESME_RINVPASWD)
+	}
+	if !c.allowed(acc) || !c.register(req.SystemID) {
+		return fail(smpp.ESME_RBINDFAIL)
+	}
+	// 3.4 及以上返回本端支持的版本
+	resp.SetScInterfaceVersion(c.Typ, c.Protocol.Version())
+	return resp, req.SystemID, nil
+}
+
+// submit 每个接收号码一个 submit_sm
+func (c *smpp_action) submit(msg *Message) ([]codec.PDU, error) {
+	var parts []*smpp.ShortMessage
+	var err error
+	if msg.Encoding == nil {
+		parts, err = smpp.NewLongMessage(msg.Text)
+	} else {
+		parts, err = smpp.NewLongMessageWithEncoding(msg.Text, msg.Encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pdus := make([]codec.PDU, 0, len(parts)*len(msg.To))
+	for _, to := range msg.To {
+		for _, sm := range parts {
+			p := smpp.NewSubmitSM().(*smpp.SubmitSM)
+			if err := p.SourceAddr.SetAddress(msg.From); err != nil {
+				return nil, err
+			}
+			if err := p.DestAddr.SetAddress(to); err != nil {
+				return nil, err
+			}
+			p.ServiceType = utils.MapItem(c.extParam, "service_type", p.ServiceType)
+			if len(parts) > 1 {
+				p.EsmClass |= smpp.SM_UDH_GSM
+			}
+			if msg.RequestReport {
+				p.RegisteredDelivery = smpp.SM_SMSC_RECEIPT_REQUESTED
+			}
+			p.Message = *sm
+			pdus = append(pdus, p)
+		}
+	}
+	return pdus, nil
+}
+
+func (c *smpp_action) submitResult(resp codec.PDU) (string, error) {
+	p, ok := resp.(*smpp.SubmitSMResp)
+	if !ok {
+		return "", smserror.ErrRespNotMatch
+	}
+	if p.CommandStatus != smpp.ESME_ROK {
+		return "", smserror.NewSmsErr(int(p.CommandStatus), "smpp.submit.error")
+	}
+	return p.MessageID, nil
+}
+
+func (c *smpp_action) report(pdu codec.PDU) (*Report, bool) {
+	p, ok := pdu.(*smpp.DeliverSM)
+	if !ok || p.Report == nil {
+		return nil, false
+	}
+	r := p.Report
+	return &Report{
+		MsgID:      r.MsgId,
+		Dest:       p.SourceAddr.Address(),
+		Stat:       r.Stat,
+		Err:        r.Err,
+		SubmitTime: r.SubmitDate,
+		DoneTime:   r.DoneDate,
+		PDU:        p,
+	}, true
+}
+
 func (c *smpp_action) active_test() error {
 	p := smpp.NewEnquireLink()
 	c.activeTestReq(p.GetSequenceNumber())
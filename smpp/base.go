@@ -11,6 +11,7 @@ import (
 type base struct {
 	Header
 	OptionalParameters codec.OptionalFields
+	tlvOrder           codec.TlvOrder
 }
 
 func newBase(commandId codec.CommandId, seqId int32) (v base) {
@@ -71,15 +72,19 @@ func (c *base) unmarshal(b *codec.BytesReader, bodyReader func(*codec.BytesReade
 	return
 }
 
+// unmarshalOptionalParam 长度不符的参数仍然保留, 读完后返回第一个错误
 func (c *base) unmarshalOptionalParam(optParam []byte) (err error) {
 	reader := codec.NewReader(optParam)
 	for reader.Len() > 0 {
 		var field codec.Field
-		if err = field.Unmarshal(reader); err == nil {
-			c.OptionalParameters[field.Tag] = field
-		} else {
-			return
+		if e := field.Unmarshal(reader); e != nil {
+			return e
+		}
+		if e := ValidateTlv(field); e != nil && err == nil {
+			err = e
 		}
+		c.OptionalParameters[field.Tag] = field
+		c.tlvOrder.Add(field.Tag)
 	}
 	return
 }
@@ -92,10 +97,8 @@ func (c *base) marshal(b *codec.BytesWriter, bodyWriter func(*codec.BytesWriter)
 		bodyWriter(bodyBuf)
 	}
 
-	// optional body
-	for _, v := range c.OptionalParameters {
-		v.Marshal(bodyBuf)
-	}
+	// optional body, 按接收或注册的顺序
+	c.tlvOrder.Marshal(bodyBuf, c.OptionalParameters)
 
 	// write header
 	c.CommandLength = uint32(PDU_HEADER_SIZE + bodyBuf.Len())
@@ -108,6 +111,7 @@ func (c *base) marshal(b *codec.BytesWriter, bodyWriter func(*codec.BytesWriter)
 // RegisterOptionalParam register optional param.
 func (c *base) RegisterOptionalParam(tlv codec.Field) {
 	c.OptionalParameters[tlv.Tag] = tlv
+	c.tlvOrder.Add(tlv.Tag)
 }
 
 // IsOk is status ok.
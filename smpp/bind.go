@@ -85,6 +85,18 @@ func (b *BindRequest) GetResponse() codec.PDU {
 	return c
 }
 
+// NegotiateVersion 服务端按 interface_version 协商会话版本, 不高于本端版本 local,
+// 为 0 时使用 local, 不支持的版本返回 false
+func (b *BindRequest) NegotiateVersion(local codec.Version) (codec.Version, bool) {
+	switch b.InterfaceVersion {
+	case V33, V34, V50:
+		return min(b.InterfaceVersion, local), true
+	case 0:
+		return local, true
+	}
+	return 0, false
+}
+
 func (c BindRequest) String() string {
 	return fmt.Sprintf("loginReq:%s uid:%s,pwd:%s,type:%s,ver:%v", c.Header, c.SystemID, c.Password, c.SystemType, c.InterfaceVersion)
 }
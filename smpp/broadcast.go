@@ -0,0 +1,275 @@
+package smpp
+
+import "github.com/zhiyin2021/zysms/codec"
+
+// BroadcastSM PDU is issued by the ESME to submit a message to the Message Centre for broadcast
+// to a specified geographical area or set of geographical areas (smpp 5.0).
+// broadcast_area_identifier, broadcast_content_type, broadcast_rep_num and
+// broadcast_frequency_interval are mandatory TLVs.
+type BroadcastSM struct {
+	base
+	ServiceType          string
+	SourceAddr           Address
+	MessageID            string
+	PriorityFlag         byte
+	ScheduleDeliveryTime string
+	ValidityPeriod       string
+	ReplaceIfPresentFlag byte
+	DataCoding           byte
+	SmDefaultMsgID       byte
+}
+
+// NewBroadcastSM returns BroadcastSM PDU.
+func NewBroadcastSM() codec.PDU {
+	c := &BroadcastSM{
+		base:                 newBase(BROADCAST_SM, 0),
+		ServiceType:          DFLT_SRVTYPE,
+		SourceAddr:           NewAddress(),
+		MessageID:            DFLT_MSGID,
+		PriorityFlag:         DFLT_PRIORITY_FLAG,
+		ScheduleDeliveryTime: DFLT_SCHEDULE,
+		ValidityPeriod:       DFLT_VALIDITY,
+		ReplaceIfPresentFlag: DFTL_REPLACE_IFP,
+		DataCoding:           DFLT_DATA_CODING,
+		SmDefaultMsgID:       DFLT_DFLTMSGID,
+	}
+	return c
+}
+
+// GetResponse implements PDU interface.
+func (c *BroadcastSM) GetResponse() codec.PDU {
+	return &BroadcastSMResp{
+		base:      newBase(BROADCAST_SM_RESP, c.SequenceNumber),
+		MessageID: DFLT_MSGID,
+	}
+}
+
+// Marshal implements PDU interface.
+func (c *BroadcastSM) Marshal(b *codec.BytesWriter) {
+	c.base.marshal(b, func(b *codec.BytesWriter) {
+		b.Grow(len(c.ServiceType) + len(c.MessageID) + len(c.ScheduleDeliveryTime) + len(c.ValidityPeriod) + 8)
+
+		b.WriteCStr(c.ServiceType)
+		c.SourceAddr.Marshal(b)
+		b.WriteCStr(c.MessageID)
+		b.WriteByte(c.PriorityFlag)
+		b.WriteCStr(c.ScheduleDeliveryTime)
+		b.WriteCStr(c.ValidityPeriod)
+		b.WriteByte(c.ReplaceIfPresentFlag)
+		b.WriteByte(c.DataCoding)
+		b.WriteByte(c.SmDefaultMsgID)
+	})
+}
+
+// Unmarshal implements PDU interface.
+func (c *BroadcastSM) Unmarshal(b *codec.BytesReader) error {
+	return c.base.unmarshal(b, func(b *codec.BytesReader) error {
+		c.ServiceType = b.ReadCStr()
+		c.SourceAddr.Unmarshal(b)
+		c.MessageID = b.ReadCStr()
+		c.PriorityFlag = b.ReadU8()
+		c.ScheduleDeliveryTime = b.ReadCStr()
+		c.ValidityPeriod = b.ReadCStr()
+		c.ReplaceIfPresentFlag = b.ReadU8()
+		c.DataCoding = b.ReadU8()
+		c.SmDefaultMsgID = b.ReadU8()
+		return b.Err()
+	})
+}
+
+// BroadcastSMResp PDU, broadcast_error_status and failed_broadcast_area_identifier
+// are returned as TLVs on failure.
+type BroadcastSMResp struct {
+	base
+	MessageID string
+}
+
+// NewBroadcastSMResp returns BroadcastSMResp.
+func NewBroadcastSMResp() codec.PDU {
+	c := &BroadcastSMResp{
+		base:      newBase(BROADCAST_SM_RESP, 0),
+		MessageID: DFLT_MSGID,
+	}
+	return c
+}
+
+// GetResponse implements PDU interface.
+func (c *BroadcastSMResp) GetResponse() codec.PDU {
+	return nil
+}
+
+// Marshal implements PDU interface.
+func (c *BroadcastSMResp) Marshal(b *codec.BytesWriter) {
+	c.base.marshal(b, func(b *codec.BytesWriter) {
+		b.Grow(len(c.MessageID) + 1)
+
+		b.WriteCStr(c.MessageID)
+	})
+}
+
+// Unmarshal implements PDU interface.
+func (c *BroadcastSMResp) Unmarshal(b *codec.BytesReader) error {
+	return c.base.unmarshal(b, func(b *codec.BytesReader) error {
+		c.MessageID = b.ReadCStr()
+		return b.Err()
+	})
+}
+
+// QueryBroadcastSM PDU is issued by the ESME to query the state of a previously submitted
+// broadcast message.
+type QueryBroadcastSM struct {
+	base
+	MessageID  string
+	SourceAddr Address
+}
+
+// NewQueryBroadcastSM returns QueryBroadcastSM PDU.
+func NewQueryBroadcastSM() codec.PDU {
+	c := &QueryBroadcastSM{
+		base:       newBase(QUERY_BROADCAST_SM, 0),
+		MessageID:  DFLT_MSGID,
+		SourceAddr: NewAddress(),
+	}
+	return c
+}
+
+// GetResponse implements PDU interface.
+func (c *QueryBroadcastSM) GetResponse() codec.PDU {
+	return &QueryBroadcastSMResp{
+		base:      newBase(QUERY_BROADCAST_SM_RESP, c.SequenceNumber),
+		MessageID: c.MessageID,
+	}
+}
+
+// Marshal implements PDU interface.
+func (c *QueryBroadcastSM) Marshal(b *codec.BytesWriter) {
+	c.base.marshal(b, func(b *codec.BytesWriter) {
+		b.Grow(len(c.MessageID) + 1)
+
+		b.WriteCStr(c.MessageID)
+		c.SourceAddr.Marshal(b)
+	})
+}
+
+// Unmarshal implements PDU interface.
+func (c *QueryBroadcastSM) Unmarshal(b *codec.BytesReader) error {
+	return c.base.unmarshal(b, func(b *codec.BytesReader) error {
+		c.MessageID = b.ReadCStr()
+		c.SourceAddr.Unmarshal(b)
+		return b.Err()
+	})
+}
+
+// QueryBroadcastSMResp PDU, message_state, broadcast_area_identifier and
+// broadcast_area_success are mandatory TLVs.
+type QueryBroadcastSMResp struct {
+	base
+	MessageID string
+}
+
+// NewQueryBroadcastSMResp returns QueryBroadcastSMResp.
+func NewQueryBroadcastSMResp() codec.PDU {
+	c := &QueryBroadcastSMResp{
+		base:      newBase(QUERY_BROADCAST_SM_RESP, 0),
+		MessageID: DFLT_MSGID,
+	}
+	return c
+}
+
+// GetResponse implements PDU interface.
+func (c *QueryBroadcastSMResp) GetResponse() codec.PDU {
+	return nil
+}
+
+// Marshal implements PDU interface.
+func (c *QueryBroadcastSMResp) Marshal(b *codec.BytesWriter) {
+	c.base.marshal(b, func(b *codec.BytesWriter) {
+		b.Grow(len(c.MessageID) + 1)
+
+		b.WriteCStr(c.MessageID)
+	})
+}
+
+// Unmarshal implements PDU interface.
+func (c *QueryBroadcastSMResp) Unmarshal(b *codec.BytesReader) error {
+	return c.base.unmarshal(b, func(b *codec.BytesReader) error {
+		c.MessageID = b.ReadCStr()
+		return b.Err()
+	})
+}
+
+// CancelBroadcastSM PDU is issued by the ESME to cancel a broadcast message which has been
+// previously submitted to the Message Centre for broadcast.
+type CancelBroadcastSM struct {
+	base
+	ServiceType string
+	MessageID   string
+	SourceAddr  Address
+}
+
+// NewCancelBroadcastSM returns CancelBroadcastSM PDU.
+func NewCancelBroadcastSM() codec.PDU {
+	c := &CancelBroadcastSM{
+		base:        newBase(CANCEL_BROADCAST_SM, 0),
+		ServiceType: DFLT_SRVTYPE,
+		MessageID:   DFLT_MSGID,
+		SourceAddr:  NewAddress(),
+	}
+	return c
+}
+
+// GetResponse implements PDU interface.
+func (c *CancelBroadcastSM) GetResponse() codec.PDU {
+	return &CancelBroadcastSMResp{
+		base: newBase(CANCEL_BROADCAST_SM_RESP, c.SequenceNumber),
+	}
+}
+
+// Marshal implements PDU interface.
+func (c *CancelBroadcastSM) Marshal(b *codec.BytesWriter) {
+	c.base.marshal(b, func(b *codec.BytesWriter) {
+		b.Grow(len(c.ServiceType) + len(c.MessageID) + 2)
+
+		b.WriteCStr(c.ServiceType)
+		b.WriteCStr(c.MessageID)
+		c.SourceAddr.Marshal(b)
+	})
+}
+
+// Unmarshal implements PDU interface.
+func (c *CancelBroadcastSM) Unmarshal(b *codec.BytesReader) error {
+	return c.base.unmarshal(b, func(b *codec.BytesReader) error {
+		c.ServiceType = b.ReadCStr()
+		c.MessageID = b.ReadCStr()
+		c.SourceAddr.Unmarshal(b)
+		return b.Err()
+	})
+}
+
+// CancelBroadcastSMResp PDU.
+type CancelBroadcastSMResp struct {
+	base
+}
+
+// NewCancelBroadcastSMResp returns CancelBroadcastSMResp.
+func NewCancelBroadcastSMResp() codec.PDU {
+	c := &CancelBroadcastSMResp{
+		base: newBase(CANCEL_BROADCAST_SM_RESP, 0),
+	}
+	return c
+}
+
+// GetResponse implements PDU interface.
+func (c *CancelBroadcastSMResp) GetResponse() codec.PDU {
+	return nil
+}
+
+// Marshal implements PDU interface.
+func (c *CancelBroadcastSMResp) Marshal(b *codec.BytesWriter) {
+	c.base.marshal(b, nil)
+}
+
+// Unmarshal implements PDU interface.
+func (c *CancelBroadcastSMResp) Unmarshal(b *codec.BytesReader) error {
+	return c.base.unmarshal(b, nil)
+}
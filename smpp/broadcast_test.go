@@ -0,0 +1,89 @@
+package smpp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+)
+
+func TestBroadcastSM(t *testing.T) {
+	p := NewBroadcastSM().(*BroadcastSM)
+	p.SequenceNumber = 7
+	var err error
+	p.SourceAddr, err = NewAddressWithTonNpiAddr(1, 1, "10086")
+	require.NoError(t, err)
+	p.MessageID = "m1"
+	p.PriorityFlag = 1
+	p.DataCoding = 8
+	p.RegisterOptionalParam(codec.NewTlv(codec.TagBroadcastAreaIdentifier, []byte{0x00, 0x12, 0x34}))
+	p.RegisterOptionalParam(codec.NewTlv(codec.TagBroadcastContentType, []byte{0x00, 0x00, 0x01}))
+	p.RegisterOptionalParam(codec.NewTlvU16(codec.TagBroadcastRepNum, 3))
+	p.RegisterOptionalParam(codec.NewTlv(codec.TagBroadcastFrequencyInterval, []byte{0x08, 0x00, 0x05}))
+
+	w := codec.NewWriter()
+	p.Marshal(w)
+	require.Equal(t, ""+
+		"0000003d"+"00000111"+"00000000"+"00000007"+ // header
+		"00"+ // service_type
+		"0101"+"313030383600"+ // source_addr_ton/npi/source_addr
+		"6d3100"+ // message_id
+		"01"+"00"+"00"+ // priority_flag, schedule_delivery_time, validity_period
+		"00"+"08"+"00"+ // replace_if_present_flag, data_coding, sm_default_msg_id
+		"06060003001234"+ // broadcast_area_identifier
+		"06010003000001"+ // broadcast_content_type
+		"060400020003"+ // broadcast_rep_num
+		"06050003080005", // broadcast_frequency_interval
+		hex.EncodeToString(w.Bytes()))
+
+	p1 := NewBroadcastSM().(*BroadcastSM)
+	require.NoError(t, p1.Unmarshal(codec.NewReader(w.Bytes())))
+	require.Equal(t, "10086", p1.SourceAddr.Address())
+	require.Equal(t, "m1", p1.MessageID)
+	require.Equal(t, byte(8), p1.DataCoding)
+	rep, ok := p1.TlvUint(codec.TagBroadcastRepNum)
+	require.True(t, ok)
+	require.Equal(t, uint32(3), rep)
+	require.Equal(t, []byte{0x08, 0x00, 0x05}, p1.OptionalParameters[codec.TagBroadcastFrequencyInterval].Data)
+
+	resp := p.GetResponse().(*BroadcastSMResp)
+	require.Equal(t, BROADCAST_SM_RESP, resp.CommandID)
+	require.Equal(t, p.SequenceNumber, resp.SequenceNumber)
+}
+
+func TestBindScInterfaceVersion(t *testing.T) {
+	tests := []struct {
+		client, local codec.Version
+		// session 协商的会话版本, sc 响应中的 sc_interface_version, 0 表示不携带
+		session, sc codec.Version
+	}{
+		{client: V33, local: V50, session: V33},
+		{client: V34, local: V50, session: V34, sc: V50},
+		{client: V50, local: V50, session: V50, sc: V50},
+		{client: V50, local: V34, session: V34, sc: V34},
+		{client: V34, local: V33, session: V33},
+	}
+	for _, tt := range tests {
+		req := NewBindRequest(Transceiver)
+		req.InterfaceVersion = tt.client
+		session, ok := req.NegotiateVersion(tt.local)
+		require.True(t, ok)
+		require.Equal(t, tt.session, session)
+
+		resp := req.GetResponse().(*BindResp)
+		resp.SetScInterfaceVersion(session, tt.local)
+		w := codec.NewWriter()
+		resp.Marshal(w)
+		resp1 := NewBindTransceiverResp().(*BindResp)
+		require.NoError(t, resp1.Unmarshal(codec.NewReader(w.Bytes())))
+		sc, ok := resp1.ScInterfaceVersion()
+		require.Equal(t, tt.sc != 0, ok, "client %x local %x", tt.client, tt.local)
+		require.Equal(t, tt.sc, sc)
+	}
+
+	req := NewBindRequest(Transceiver)
+	req.InterfaceVersion = 0x40
+	_, ok := req.NegotiateVersion(V50)
+	require.False(t, ok)
+}
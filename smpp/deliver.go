@@ -1,7 +1,6 @@
 package smpp
 
 import (
-	"bytes"
 	"fmt"
 	"strings"
 	"time"
@@ -163,12 +162,10 @@ type DeliverReport struct {
 
 func (c *DeliverSM) decodeReport() {
 	c.Report = &DeliverReport{}
-	if v1, ok := c.OptionalParameters[codec.TagMessageStateOption]; ok && len(v1.Data) == 1 {
-		c.Report.Stat = optionalMessageState(v1.Data[0])
+	if state, ok := c.MessageState(); ok {
+		c.Report.Stat = optionalMessageState(state)
 		if c.Report.Stat != "" {
-			if v2, ok := c.OptionalParameters[codec.TagReceiptedMessageID]; ok {
-				c.Report.MsgId = string(bytes.Trim(v2.Data, "\x00"))
-			}
+			c.Report.MsgId = c.ReceiptedMessageID()
 			c.Report.DoneDate = time.Now().Format("0601021504")
 			if c.Report.MsgId != "" {
 				return
@@ -31,6 +31,10 @@ func (c Header) String() string {
 	return fmt.Sprintf("0x%.8x,%d,%d,%d", c.CommandID, c.SequenceNumber, c.CommandStatus, c.CommandLength)
 }
 
+func (c *Header) GetCommandID() uint32 {
+	return uint32(c.CommandID)
+}
+
 // ParseHeader parses PDU header.
 func ParseHeader(v [16]byte) (h Header) {
 	h.CommandLength = binary.BigEndian.Uint32(v[:])
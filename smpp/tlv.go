@@ -0,0 +1,201 @@
+package smpp
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/zhiyin2021/zysms/codec"
+)
+
+// TlvSpecs smpp 3.4/5.0 可选参数的类型及长度, 未登记的 tag 不校验
+var TlvSpecs = map[codec.Tag]codec.TlvSpec{
+	codec.TagDestAddrSubunit:          {Name: "dest_addr_subunit", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagDestNetworkType:          {Name: "dest_network_type", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagDestBearerType:           {Name: "dest_bearer_type", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagDestTelematicsID:         {Name: "dest_telematics_id", Type: codec.TlvUint16, Min: 2, Max: 2},
+	codec.TagSourceAddrSubunit:        {Name: "source_addr_subunit", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagSourceNetworkType:        {Name: "source_network_type", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagSourceBearerType:         {Name: "source_bearer_type", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagSourceTelematicsID:       {Name: "source_telematics_id", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagQosTimeToLive:            {Name: "qos_time_to_live", Type: codec.TlvUint32, Min: 4, Max: 4},
+	codec.TagPayloadType:              {Name: "payload_type", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagAdditionalStatusInfoText: {Name: "additional_status_info_text", Type: codec.TlvCStr, Min: 1, Max: 256},
+	codec.TagReceiptedMessageID:       {Name: "receipted_message_id", Type: codec.TlvCStr, Min: 1, Max: 65},
+	codec.TagMsMsgWaitFacilities:      {Name: "ms_msg_wait_facilities", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagPrivacyIndicator:         {Name: "privacy_indicator", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagSourceSubaddress:         {Name: "source_subaddress", Type: codec.TlvOctet, Min: 2, Max: 23},
+	codec.TagDestSubaddress:           {Name: "dest_subaddress", Type: codec.TlvOctet, Min: 2, Max: 23},
+	codec.TagUserMessageReference:     {Name: "user_message_reference", Type: codec.TlvUint16, Min: 2, Max: 2},
+	codec.TagUserResponseCode:         {Name: "user_response_code", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagSourcePort:               {Name: "source_port", Type: codec.TlvUint16, Min: 2, Max: 2},
+	codec.TagDestinationPort:          {Name: "destination_port", Type: codec.TlvUint16, Min: 2, Max: 2},
+	codec.TagSarMsgRefNum:             {Name: "sar_msg_ref_num", Type: codec.TlvUint16, Min: 2, Max: 2},
+	codec.TagLanguageIndicator:        {Name: "language_indicator", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagSarTotalSegments:         {Name: "sar_total_segments", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagSarSegmentSeqnum:         {Name: "sar_segment_seqnum", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagScInterfaceVersion:       {Name: "sc_interface_version", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagCallbackNumPresInd:       {Name: "callback_num_pres_ind", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagCallbackNumAtag:          {Name: "callback_num_atag", Type: codec.TlvOctet, Min: 0, Max: 65},
+	codec.TagNumberOfMessages:         {Name: "number_of_messages", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagCallbackNum:              {Name: "callback_num", Type: codec.TlvOctet, Min: 4, Max: 19},
+	codec.TagDpfResult:                {Name: "dpf_result", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagSetDpf:                   {Name: "set_dpf", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagMsAvailabilityStatus:     {Name: "ms_availability_status", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagNetworkErrorCode:         {Name: "network_error_code", Type: codec.TlvOctet, Min: 3, Max: 3},
+	codec.TagMessagePayload:           {Name: "message_payload", Type: codec.TlvOctet, Min: 0, Max: 65535},
+	codec.TagDeliveryFailureReason:    {Name: "delivery_failure_reason", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagMoreMessagesToSend:       {Name: "more_messages_to_send", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagMessageStateOption:       {Name: "message_state", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagCongestionState:          {Name: "congestion_state", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagUssdServiceOp:            {Name: "ussd_service_op", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagDisplayTime:              {Name: "display_time", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagSmsSignal:                {Name: "sms_signal", Type: codec.TlvUint16, Min: 2, Max: 2},
+	codec.TagMsValidity:               {Name: "ms_validity", Type: codec.TlvOctet, Min: 1, Max: 4},
+	codec.TagAlertOnMessageDelivery:   {Name: "alert_on_message_delivery", Type: codec.TlvOctet, Min: 0, Max: 1},
+	codec.TagItsReplyType:             {Name: "its_reply_type", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagItsSessionInfo:           {Name: "its_session_info", Type: codec.TlvOctet, Min: 2, Max: 2},
+	// smpp 5.0
+	codec.TagBroadcastChannelIndicator:  {Name: "broadcast_channel_indicator", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagBroadcastContentType:       {Name: "broadcast_content_type", Type: codec.TlvOctet, Min: 3, Max: 3},
+	codec.TagBroadcastContentTypeInfo:   {Name: "broadcast_content_type_info", Type: codec.TlvOctet, Min: 1, Max: 255},
+	codec.TagBroadcastMessageClass:      {Name: "broadcast_message_class", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagBroadcastRepNum:            {Name: "broadcast_rep_num", Type: codec.TlvUint16, Min: 2, Max: 2},
+	codec.TagBroadcastFrequencyInterval: {Name: "broadcast_frequency_interval", Type: codec.TlvOctet, Min: 3, Max: 3},
+	codec.TagBroadcastAreaIdentifier:    {Name: "broadcast_area_identifier", Type: codec.TlvOctet, Min: 1, Max: 101},
+	codec.TagBroadcastErrorStatus:       {Name: "broadcast_error_status", Type: codec.TlvUint32, Min: 4, Max: 4},
+	codec.TagBroadcastAreaSuccess:       {Name: "broadcast_area_success", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagBroadcastEndTime:           {Name: "broadcast_end_time", Type: codec.TlvCStr, Min: 1, Max: 17},
+	codec.TagBroadcastServiceGroup:      {Name: "broadcast_service_group", Type: codec.TlvOctet, Min: 1, Max: 255},
+	codec.TagBillingIdentification:      {Name: "billing_identification", Type: codec.TlvOctet, Min: 1, Max: 1024},
+	codec.TagSourceNetworkID:            {Name: "source_network_id", Type: codec.TlvCStr, Min: 7, Max: 66},
+	codec.TagDestNetworkID:              {Name: "dest_network_id", Type: codec.TlvCStr, Min: 7, Max: 66},
+	codec.TagSourceNodeID:               {Name: "source_node_id", Type: codec.TlvOctet, Min: 6, Max: 6},
+	codec.TagDestNodeID:                 {Name: "dest_node_id", Type: codec.TlvOctet, Min: 6, Max: 6},
+	codec.TagDestAddrNpResolution:       {Name: "dest_addr_np_resolution", Type: codec.TlvUint8, Min: 1, Max: 1},
+	codec.TagDestAddrNpInformation:      {Name: "dest_addr_np_information", Type: codec.TlvOctet, Min: 10, Max: 10},
+	codec.TagDestAddrNpCountry:          {Name: "dest_addr_np_country", Type: codec.TlvOctet, Min: 1, Max: 5},
+}
+
+// ValidateTlv 按 TlvSpecs 校验长度
+func ValidateTlv(f codec.Field) error {
+	if spec, ok := TlvSpecs[f.Tag]; ok {
+		return spec.Check(f)
+	}
+	return nil
+}
+
+// SetTlv 校验后设置可选参数
+func (c *base) SetTlv(f codec.Field) error {
+	if err := ValidateTlv(f); err != nil {
+		return err
+	}
+	c.RegisterOptionalParam(f)
+	return nil
+}
+
+// TlvUint 整数类型的可选参数, 不存在或长度不符时 ok 为 false
+func (c *base) TlvUint(tag codec.Tag) (v uint32, ok bool) {
+	f, ok := c.OptionalParameters[tag]
+	if !ok || ValidateTlv(f) != nil {
+		return 0, false
+	}
+	switch len(f.Data) {
+	case 1:
+		return uint32(f.Data[0]), true
+	case 2:
+		return uint32(binary.BigEndian.Uint16(f.Data)), true
+	case 4:
+		return binary.BigEndian.Uint32(f.Data), true
+	}
+	return 0, false
+}
+
+// TlvStr 字符串类型的可选参数, 去掉结尾的 0
+func (c *base) TlvStr(tag codec.Tag) (string, bool) {
+	f, ok := c.OptionalParameters[tag]
+	if !ok || ValidateTlv(f) != nil {
+		return "", false
+	}
+	return string(bytes.TrimRight(f.Data, "\x00")), true
+}
+
+// ReceiptedMessageID 状态报告对应的消息ID
+func (c *base) ReceiptedMessageID() string {
+	v, _ := c.TlvStr(codec.TagReceiptedMessageID)
+	return v
+}
+
+func (c *base) SetReceiptedMessageID(id string) error {
+	return c.SetTlv(codec.NewTlvCStr(codec.TagReceiptedMessageID, id))
+}
+
+// MessageState 状态报告中的消息状态, 见 optionalMessageState
+func (c *base) MessageState() (byte, bool) {
+	v, ok := c.TlvUint(codec.TagMessageStateOption)
+	return byte(v), ok
+}
+
+func (c *base) SetMessageState(v byte) {
+	c.RegisterOptionalParam(codec.NewTlvU8(codec.TagMessageStateOption, v))
+}
+
+// NetworkErrorCode 网络类型(1:ANSI-136 2:IS-95 3:GSM ...)及错误码
+func (c *base) NetworkErrorCode() (typ byte, code uint16, ok bool) {
+	f, ok := c.OptionalParameters[codec.TagNetworkErrorCode]
+	if !ok || len(f.Data) != 3 {
+		return 0, 0, false
+	}
+	return f.Data[0], binary.BigEndian.Uint16(f.Data[1:]), true
+}
+
+func (c *base) SetNetworkErrorCode(typ byte, code uint16) {
+	c.RegisterOptionalParam(codec.NewTlv(codec.TagNetworkErrorCode, binary.BigEndian.AppendUint16([]byte{typ}, code)))
+}
+
+// SarInfo 长短信参考号, 总条数及序号
+func (c *base) SarInfo() (ref uint16, total, seq byte, ok bool) {
+	r, ok1 := c.TlvUint(codec.TagSarMsgRefNum)
+	t, ok2 := c.TlvUint(codec.TagSarTotalSegments)
+	s, ok3 := c.TlvUint(codec.TagSarSegmentSeqnum)
+	return uint16(r), byte(t), byte(s), ok1 && ok2 && ok3
+}
+
+func (c *base) SetSarInfo(ref uint16, total, seq byte) {
+	c.RegisterOptionalParam(codec.NewTlvU16(codec.TagSarMsgRefNum, ref))
+	c.RegisterOptionalParam(codec.NewTlvU8(codec.TagSarTotalSegments, total))
+	c.RegisterOptionalParam(codec.NewTlvU8(codec.TagSarSegmentSeqnum, seq))
+}
+
+// MessagePayload 替代 short_message 的消息内容
+func (c *base) MessagePayload() []byte {
+	return c.OptionalParameters[codec.TagMessagePayload].Data
+}
+
+func (c *base) SetMessagePayload(data []byte) error {
+	return c.SetTlv(codec.NewTlv(codec.TagMessagePayload, data))
+}
+
+// ScInterfaceVersion 服务端支持的最高版本, bind 响应中携带
+func (c *base) ScInterfaceVersion() (codec.Version, bool) {
+	v, ok := c.TlvUint(codec.TagScInterfaceVersion)
+	return codec.Version(v), ok
+}
+
+// SetScInterfaceVersion 会话版本 session 为 3.4 及以上时携带本端版本 local, 3.3 不支持 TLV
+func (c *BindResp) SetScInterfaceVersion(session, local codec.Version) {
+	if session >= V34 {
+		c.RegisterOptionalParam(codec.NewTlv(codec.TagScInterfaceVersion, []byte{byte(local)}))
+	}
+}
+
+// Ports 应用端口寻址
+func (c *base) Ports() (src, dst uint16, ok bool) {
+	s, ok1 := c.TlvUint(codec.TagSourcePort)
+	d, ok2 := c.TlvUint(codec.TagDestinationPort)
+	return uint16(s), uint16(d), ok1 && ok2
+}
+
+func (c *base) SetPorts(src, dst uint16) {
+	c.RegisterOptionalParam(codec.NewTlvU16(codec.TagSourcePort, src))
+	c.RegisterOptionalParam(codec.NewTlvU16(codec.TagDestinationPort, dst))
+}
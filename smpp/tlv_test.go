@@ -0,0 +1,67 @@
+package smpp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+func TestTlvAccessors(t *testing.T) {
+	p := NewDeliverSM().(*DeliverSM)
+	require.NoError(t, p.SetReceiptedMessageID("abc123"))
+	p.SetMessageState(2)
+	p.SetNetworkErrorCode(3, 0x0101)
+	p.SetSarInfo(0x1234, 3, 2)
+	p.SetPorts(1000, 2000)
+	require.Error(t, p.SetReceiptedMessageID(string(bytes.Repeat([]byte("x"), 65))))
+
+	w := codec.NewWriter()
+	p.Marshal(w)
+	data := w.Bytes()
+	// 多次序列化结果一致
+	for i := 0; i < 10; i++ {
+		w := codec.NewWriter()
+		p.Marshal(w)
+		require.Equal(t, data, w.Bytes())
+	}
+
+	p1 := NewDeliverSM().(*DeliverSM)
+	require.NoError(t, p1.Unmarshal(codec.NewReader(data)))
+	require.Equal(t, "abc123", p1.ReceiptedMessageID())
+	state, ok := p1.MessageState()
+	require.True(t, ok)
+	require.Equal(t, byte(2), state)
+	typ, code, ok := p1.NetworkErrorCode()
+	require.True(t, ok)
+	require.Equal(t, byte(3), typ)
+	require.Equal(t, uint16(0x0101), code)
+	ref, total, seq, ok := p1.SarInfo()
+	require.True(t, ok)
+	require.Equal(t, []any{uint16(0x1234), byte(3), byte(2)}, []any{ref, total, seq})
+	src, dst, ok := p1.Ports()
+	require.True(t, ok)
+	require.Equal(t, []uint16{1000, 2000}, []uint16{src, dst})
+}
+
+func TestTlvValidate(t *testing.T) {
+	p := NewSubmitSM().(*SubmitSM)
+	// sar_total_segments 应为 1 字节
+	p.RegisterOptionalParam(codec.NewTlvU16(codec.TagSarTotalSegments, 3))
+	p.RegisterOptionalParam(codec.NewTlvU16(codec.TagUserMessageReference, 7))
+	w := codec.NewWriter()
+	p.Marshal(w)
+
+	p1 := NewSubmitSM().(*SubmitSM)
+	err := p1.Unmarshal(codec.NewReader(w.Bytes()))
+	require.True(t, errors.Is(err, smserror.ErrInvalidTlv), err)
+	// 其余参数仍然可用
+	v, ok := p1.TlvUint(codec.TagUserMessageReference)
+	require.True(t, ok)
+	require.Equal(t, uint32(7), v)
+	_, ok = p1.TlvUint(codec.TagSarTotalSegments)
+	require.False(t, ok)
+}
@@ -41,6 +41,7 @@ const (
 	// Interface_Version
 	V33 codec.Version = 0x33
 	V34 codec.Version = 0x34
+	V50 codec.Version = 0x50
 
 	// Address_TON
 	GSM_TON_UNKNOWN       = byte(0x00)
@@ -421,6 +422,14 @@ const (
 	ALERT_NOTIFICATION codec.CommandId = 0x00000102
 	DATA_SM            codec.CommandId = 0x00000103
 	DATA_SM_RESP       codec.CommandId = 0x80000103
+
+	// smpp 5.0
+	BROADCAST_SM             codec.CommandId = 0x00000111
+	BROADCAST_SM_RESP        codec.CommandId = 0x80000111
+	QUERY_BROADCAST_SM       codec.CommandId = 0x00000112
+	QUERY_BROADCAST_SM_RESP  codec.CommandId = 0x80000112
+	CANCEL_BROADCAST_SM      codec.CommandId = 0x00000113
+	CANCEL_BROADCAST_SM_RESP codec.CommandId = 0x80000113
 )
 
 // nolint
@@ -561,6 +570,30 @@ const (
 	ESME_LAST_ERROR codec.CommandStatus = 0x0000012C // THE VALUE OF THE LAST ERROR CODE
 )
 
+// nolint
+const (
+	// smpp 5.0 Command_Status Error Codes
+	ESME_RSERTYPUNAUTH       codec.CommandStatus = 0x00000100 + iota // ESME Not authorised to use specified service_type
+	ESME_RPROHIBITED                                                 // ESME Prohibited from using specified operation
+	ESME_RSERTYPUNAVAIL                                              // Specified service_type is unavailable
+	ESME_RSERTYPDENIED                                               // Specified service_type is denied
+	ESME_RINVDCS                                                     // Invalid Data Coding Scheme
+	ESME_RINVSRCADDRSUBUNIT                                          // Source Address Sub unit is Invalid
+	ESME_RINVDSTADDRSUBUNIT                                          // Destination Address Sub unit is Invalid
+	ESME_RINVBCASTFREQINT                                            // Broadcast Frequency Interval is invalid
+	ESME_RINVBCASTALIAS_NAME                                         // Broadcast Alias Name is invalid
+	ESME_RINVBCASTAREAFMT                                            // Broadcast Area Format is invalid
+	ESME_RINVNUMBCAST_AREAS                                          // Number of Broadcast Areas is invalid
+	ESME_RINVBCASTCNTTYPE                                            // Broadcast Content Type is invalid
+	ESME_RINVBCASTMSGCLASS                                           // Broadcast Message Class is invalid
+	ESME_RBCASTFAIL                                                  // broadcast_sm operation failed
+	ESME_RBCASTQUERYFAIL                                             // query_broadcast_sm operation failed
+	ESME_RBCASTCANCELFAIL                                            // cancel_broadcast_sm operation failed
+	ESME_RINVBCAST_REP                                               // Number of Repeated Broadcasts is invalid
+	ESME_RINVBCASTSRVGRP                                             // Broadcast Service Group is invalid
+	ESME_RINVBCASTCHANIND                                            // Broadcast Channel Indicator is invalid
+)
+
 type pduGenerator func() codec.PDU
 
 // CreatePDUFromCmdID creates PDU from cmd id.
@@ -621,6 +654,18 @@ func CreatePDUFromCmdID(cmdID codec.CommandId) (codec.PDU, error) {
 		return &AlertNotification{base: base}, nil
 	case GENERIC_NACK:
 		return &GenericNack{base: base}, nil
+	case BROADCAST_SM:
+		return &BroadcastSM{base: base}, nil
+	case BROADCAST_SM_RESP:
+		return &BroadcastSMResp{base: base}, nil
+	case QUERY_BROADCAST_SM:
+		return &QueryBroadcastSM{base: base}, nil
+	case QUERY_BROADCAST_SM_RESP:
+		return &QueryBroadcastSMResp{base: base}, nil
+	case CANCEL_BROADCAST_SM:
+		return &CancelBroadcastSM{base: base}, nil
+	case CANCEL_BROADCAST_SM_RESP:
+		return &CancelBroadcastSMResp{base: base}, nil
 	default:
 		return nil, smserror.ErrUnknownCommandID
 	}
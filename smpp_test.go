@@ -0,0 +1,158 @@
+package zysms
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/enum"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
+	"go.uber.org/zap"
+)
+
+// badTlvSubmit sar_msg_ref_num 应为 2 字节
+func badTlvSubmit() *smpp.SubmitSM {
+	p := smpp.NewSubmitSM().(*smpp.SubmitSM)
+	p.RegisterOptionalParam(codec.NewTlv(codec.TagSarMsgRefNum, []byte{1}))
+	return p
+}
+
+func TestSmppInvalidTlv(t *testing.T) {
+	s, l := testListen(t, codec.SMPP34)
+	s.OnRecv = func(c Conn, p PDU) {
+		t.Errorf("unexpected %T", p)
+	}
+
+	// 未登录时按状态拒绝
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	req := badTlvSubmit()
+	writePDU(t, conn, req)
+	resp, err := smpp.Parse(conn, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	require.Equal(t, req.GetSequenceNumber(), resp.GetSequenceNumber())
+	require.Equal(t, smpp.ESME_RINVBNDSTS, resp.(*smpp.GenericNack).CommandStatus)
+
+	// 已登录时每个请求回复 ESME_RINVOPTPARAMVAL, 连接保持
+	_, c := testDial(t, codec.SMPP34, l, nil)
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		resp, err := c.Request(ctx, badTlvSubmit())
+		cancel()
+		require.NoError(t, err)
+		require.Equal(t, smpp.ESME_RINVOPTPARAMVAL, resp.(*smpp.SubmitSMResp).CommandStatus)
+	}
+	require.True(t, c.IsConnected())
+}
+
+// outbindPeer 监听 outbind 的客户端, 只接受账号 smsc/smscpwd 的服务端
+func outbindPeer(t *testing.T) (*SMS, *Listener) {
+	t.Helper()
+	s := New(codec.SMPP34)
+	s.Authenticator = AuthenticatorFunc(func(conn Conn, id string) (*Account, error) {
+		if id != "smsc" {
+			return nil, nil
+		}
+		return &Account{ID: id, Password: "smscpwd"}, nil
+	})
+	s.OnRecv = func(c Conn, p PDU) {
+		if d, ok := p.(*smpp.DeliverSM); ok {
+			c.SendPDU(d.GetResponse())
+		}
+	}
+	l, err := s.ListenOutbind("127.0.0.1:0", testUid, testPwd)
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownNow(s) })
+	return s, l
+}
+
+func TestSmppOutbind(t *testing.T) {
+	_, cl := outbindPeer(t)
+	srv := New(codec.SMPP34)
+	srv.Authenticator = testAuth
+	t.Cleanup(func() { shutdownNow(srv) })
+
+	sc, err := srv.Outbind(cl.Addr().String(), "smsc", "smscpwd", time.Second, nil)
+	require.NoError(t, err)
+	// 客户端以 bind_receiver 登录
+	require.Eventually(t, func() bool { return sc.BindState() == enum.BIND_RX }, time.Second, 5*time.Millisecond)
+	c := serverConn(t, cl)
+	require.Equal(t, enum.BIND_RX, c.BindState())
+
+	// 接收方式可以下发 deliver_sm
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := sc.(*sms_conn).Request(ctx, smpp.NewDeliverSM())
+	require.NoError(t, err)
+	require.IsType(t, &smpp.DeliverSMResp{}, resp)
+}
+
+func TestSmppOutbindBadCredentials(t *testing.T) {
+	s, cl := outbindPeer(t)
+	errs := make(chan error, 1)
+	s.OnError = func(c Conn, err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	srv := New(codec.SMPP34)
+	srv.Authenticator = testAuth
+	t.Cleanup(func() { shutdownNow(srv) })
+
+	sc, err := srv.Outbind(cl.Addr().String(), "smsc", "bad", time.Second, nil)
+	require.NoError(t, err)
+	// 客户端拒绝服务端账号并断开, 不发起 bind_receiver
+	var e *smserror.SmsError
+	require.ErrorAs(t, <-errs, &e)
+	require.Equal(t, int(smpp.ESME_RBINDFAIL), e.Code)
+	require.Eventually(t, func() bool { return !sc.IsConnected() }, time.Second, 5*time.Millisecond)
+	require.Equal(t, enum.BIND_CLOSED, sc.BindState())
+}
+
+func TestSmppBindType(t *testing.T) {
+	tests := []struct {
+		bindType string
+		state    int32
+	}{
+		{"tx", enum.BIND_TX},
+		{"rx", enum.BIND_RX},
+		{"trx", enum.BIND_TRX},
+		{"", enum.BIND_TRX},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bindType, func(t *testing.T) {
+			_, l := testListen(t, codec.SMPP34)
+			_, c := testDial(t, codec.SMPP34, l, map[string]string{"bind_type": tt.bindType})
+			require.Equal(t, tt.state, c.BindState())
+			// 服务端按收到的 bind 命令设置状态
+			require.Equal(t, tt.state, serverConn(t, l).BindState())
+		})
+	}
+}
+
+func TestSmppDialPair(t *testing.T) {
+	_, l := testListen(t, codec.SMPP34)
+	s := New(codec.SMPP34)
+	t.Cleanup(func() { shutdownNow(s) })
+	tx, rx, err := s.DialPair(l.Addr().String(), testUid, testPwd, time.Second, nil)
+	require.NoError(t, err)
+	require.Equal(t, enum.BIND_TX, tx.BindState())
+	require.Equal(t, enum.BIND_RX, rx.BindState())
+	require.Eventually(t, func() bool {
+		states := map[int32]int{}
+		for _, c := range l.Conns().All() {
+			states[c.BindState()]++
+		}
+		return states[enum.BIND_TX] == 1 && states[enum.BIND_RX] == 1
+	}, time.Second, 5*time.Millisecond)
+
+	_, _, err = New(codec.CMPP30).DialPair(l.Addr().String(), testUid, testPwd, time.Second, nil)
+	require.ErrorIs(t, err, smserror.ErrProtoNotSupport)
+}
@@ -55,6 +55,40 @@ var (
 
 	// ErrUDHTooLong UDH-L is larger than total length of short message data
 	ErrUDHTooLong = NewSmsErr(20, "user Data Header is too long for PDU short message")
+
+	// ErrRequestTimeout indicates the response of a request did not arrive in time.
+	ErrRequestTimeout = NewSmsErr(21, "wait response timeout")
+
+	// ErrWindowFull indicates the send window is full and window_reject is enabled.
+	ErrWindowFull = NewSmsErr(22, "send window is full")
+
+	// ErrSessionDown indicates the session is not bound and send queue is disabled.
+	ErrSessionDown = NewSmsErr(23, "session is not bound")
+
+	// ErrSessionQueueFull indicates the send queue of an unbound session is full.
+	ErrSessionQueueFull = NewSmsErr(24, "session send queue is full")
+
+	// ErrSessionClosed indicates the session has been closed.
+	ErrSessionClosed = NewSmsErr(25, "session is closed")
+
+	// ErrNotBound indicates a non-login PDU was sent or received before login.
+	ErrNotBound = NewSmsErr(26, "connect first")
+
+	// ErrInvalidBindState indicates the PDU is not allowed in the current bind state.
+	ErrInvalidBindState = NewSmsErr(27, "invalid bind state for command")
+
+	// ErrInvalidTlv indicates an optional parameter violates its length rule.
+	ErrInvalidTlv = NewSmsErr(28, "invalid optional parameter")
+
+	// ErrCancelFailed indicates the gateway refused to cancel the message.
+	ErrCancelFailed = NewSmsErr(29, "cancel message failed")
+
+	// ErrServerClosed indicates SMS.Shutdown has been called.
+	ErrServerClosed = NewSmsErr(30, "sms is shut down")
+
+	// ErrAddrNotAllowed indicates the remote address is rejected by the ACL.
+	ErrAddrNotAllowed = NewSmsErr(31, "remote address not allowed")
+
 	// Errors for connect resp status.
 
 	ErrnoConnInvalidStruct  uint8 = 1
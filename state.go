@@ -0,0 +1,157 @@
+package zysms
+
+import (
+	"sync/atomic"
+
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/enum"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+// BindState 连接状态 enum.BIND_*
+func (c *sms_conn) BindState() int32 {
+	return atomic.LoadInt32(&c.bindState)
+}
+
+func (c *sms_conn) setBindState(state int32) {
+	atomic.StoreInt32(&c.bindState, state)
+}
+
+// checkBindState 校验当前状态是否允许该报文, 返回 ErrNotBound 或 ErrInvalidBindState
+func (c *sms_conn) checkBindState(pdu PDU) error {
+	if isResponse(pdu) || isSessionPDU(pdu) {
+		return nil
+	}
+	switch c.BindState() {
+	case enum.BIND_OPEN:
+		return smserror.ErrNotBound
+	case enum.BIND_RX:
+		// 接收方式登录不能提交短信
+		switch pdu.(type) {
+		case *smpp.SubmitSM, *smpp.SubmitMulti, *smpp.ReplaceSM, *smpp.CancelSM, *smpp.QuerySM,
+			*smpp.BroadcastSM, *smpp.QueryBroadcastSM, *smpp.CancelBroadcastSM:
+			return smserror.ErrInvalidBindState
+		}
+	case enum.BIND_TX:
+		// 发送方式登录不能接收短信
+		if _, ok := pdu.(*smpp.DeliverSM); ok {
+			return smserror.ErrInvalidBindState
+		}
+	case enum.BIND_UNBINDING, enum.BIND_CLOSED:
+		return smserror.ErrInvalidBindState
+	}
+	return nil
+}
+
+// rejectByState 收到当前状态不允许的报文, SMPP 回复 generic_nack 后继续,
+// 其他协议回复带错误码的响应后断开连接, 见 rejectResp.
+// 本端退出过程中对端尚未收到退出请求, 其请求仍交给 OnRecv 处理
+func (c *sms_conn) rejectByState(pdu PDU) (bool, error) {
+	if c.BindState() == enum.BIND_UNBINDING {
+		return false, nil
+	}
+	err := c.checkBindState(pdu)
+	if err == nil {
+		return false, nil
+	}
+	if _, ok := pdu.GetHeader().(*smpp.Header); ok {
+		nack := smpp.NewGenericNack().(*smpp.GenericNack)
+		nack.SequenceNumber = pdu.GetSequenceNumber()
+		nack.CommandStatus = smpp.ESME_RINVBNDSTS
+		c.logger.Warnf("reject %T: %s", pdu, err)
+		return true, c.SendPDU(nack)
+	}
+	if resp := rejectResp(pdu); resp != nil {
+		c.logger.Warnf("reject %T: %s", pdu, err)
+		c.SendPDU(resp)
+	}
+	return true, err
+}
+
+// rejectResp 状态不允许的请求的响应: cmpp 2 命令字错, smgp 21 认证错, sgip 1 非法登录.
+// 响应不带状态(如查询)时返回 nil
+func rejectResp(pdu PDU) PDU {
+	resp := pdu.GetResponse()
+	switch p := resp.(type) {
+	case *cmpp.SubmitResp:
+		p.Result = 2
+	case *cmpp.DeliverResp:
+		p.Result = 2
+	case *cmpp.CancelResp:
+		p.SuccessId = 1
+	case *smgp.SubmitResp:
+		p.Status = 21
+	case *smgp.DeliverResp:
+		p.Status = 21
+	case *smgp.ForwardResp:
+		p.Status = 21
+	case *smgp.MTRouteUpdateResp:
+		p.Status = 21
+	case *smgp.MORouteUpdateResp:
+		p.Status = 21
+	case *sgip.SubmitResp:
+		p.Status = 1
+	case *sgip.DeliverResp:
+		p.Status = 1
+	case *sgip.ReportResp:
+		p.Status = 1
+	case *sgip.UserRptResp:
+		p.Status = 1
+	default:
+		return nil
+	}
+	return resp
+}
+
+// onSent 发送登录成功响应后进入登录状态
+func (c *sms_conn) onSent(pdu PDU) {
+	state := int32(-1)
+	switch p := pdu.(type) {
+	case *cmpp.ConnResp:
+		if p.Status == 0 {
+			state = enum.BIND_TRX
+		}
+	case *smgp.LoginResp:
+		if p.Status == 0 {
+			state = enum.BIND_TRX
+		}
+	case *sgip.BindResp:
+		if p.Status == 0 {
+			state = enum.BIND_TRX
+		}
+	case *smpp.BindResp:
+		if p.CommandStatus == smpp.ESME_ROK {
+			state = smppBindState(p.CommandID)
+		}
+	}
+	if state >= 0 && c.BindState() == enum.BIND_OPEN {
+		c.IsAuth = true
+		c.setBindState(state)
+	}
+}
+
+func smppBindState(id codec.CommandId) int32 {
+	switch id {
+	case smpp.BIND_TRANSMITTER, smpp.BIND_TRANSMITTER_RESP:
+		return enum.BIND_TX
+	case smpp.BIND_RECEIVER, smpp.BIND_RECEIVER_RESP:
+		return enum.BIND_RX
+	}
+	return enum.BIND_TRX
+}
+
+// isSessionPDU 任何状态下都允许的登录、退出及心跳报文
+func isSessionPDU(pdu PDU) bool {
+	switch pdu.(type) {
+	case *cmpp.ConnReq, *cmpp.TerminateReq, *cmpp.ActiveTestReq,
+		*smgp.LoginReq, *smgp.ExitReq, *smgp.ActiveTestReq,
+		*sgip.BindReq, *sgip.UnbindReq,
+		*smpp.BindRequest, *smpp.Unbind, *smpp.EnquireLink, *smpp.Outbind, *smpp.GenericNack:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package zysms
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"go.uber.org/zap"
+)
+
+func TestRejectBeforeLogin(t *testing.T) {
+	nop := zap.NewNop().Sugar()
+	tests := []struct {
+		proto codec.SmsProto
+		req   codec.PDU
+		parse func(io.Reader) (codec.PDU, error)
+		check func(t *testing.T, resp codec.PDU)
+		// closed 回复后是否断开
+		closed bool
+	}{
+		{
+			proto:  codec.CMPP30,
+			req:    cmpp.NewSubmitReq(cmpp.V30),
+			parse:  func(r io.Reader) (codec.PDU, error) { return cmpp.Parse(r, cmpp.V30, nop) },
+			check:  func(t *testing.T, resp codec.PDU) { require.Equal(t, uint32(2), resp.(*cmpp.SubmitResp).Result) },
+			closed: true,
+		},
+		{
+			proto:  codec.SMGP30,
+			req:    smgp.NewSubmitReq(smgp.V30),
+			parse:  func(r io.Reader) (codec.PDU, error) { return smgp.Parse(r, smgp.V30, nop) },
+			check:  func(t *testing.T, resp codec.PDU) { require.Equal(t, smgp.Status(21), resp.(*smgp.SubmitResp).Status) },
+			closed: true,
+		},
+		{
+			proto:  codec.SGIP,
+			req:    sgip.NewSubmitReq(sgip.V12, 0),
+			parse:  func(r io.Reader) (codec.PDU, error) { return sgip.Parse(r, sgip.V12, 0) },
+			check:  func(t *testing.T, resp codec.PDU) { require.Equal(t, sgip.Status(1), resp.(*sgip.SubmitResp).Status) },
+			closed: true,
+		},
+		{
+			// 心跳只回复一次, 不能先回复成功再拒绝
+			proto:  codec.SGIP,
+			req:    sgip.NewReportReq(sgip.V12, 0),
+			parse:  func(r io.Reader) (codec.PDU, error) { return sgip.Parse(r, sgip.V12, 0) },
+			check:  func(t *testing.T, resp codec.PDU) { require.Equal(t, sgip.Status(1), resp.(*sgip.ReportResp).Status) },
+			closed: true,
+		},
+		{
+			proto: codec.SMPP34,
+			req:   smpp.NewSubmitSM(),
+			parse: func(r io.Reader) (codec.PDU, error) { return smpp.Parse(r, nop) },
+			check: func(t *testing.T, resp codec.PDU) {
+				require.Equal(t, smpp.ESME_RINVBNDSTS, resp.(*smpp.GenericNack).CommandStatus)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%T", tt.proto, tt.req), func(t *testing.T) {
+			_, l := testListen(t, tt.proto)
+			conn, err := net.Dial("tcp", l.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(time.Second))
+
+			writePDU(t, conn, tt.req)
+			resp, err := tt.parse(conn)
+			require.NoError(t, err)
+			require.Equal(t, tt.req.GetSequenceNumber(), resp.GetSequenceNumber())
+			tt.check(t, resp)
+			if tt.closed {
+				_, err = conn.Read(make([]byte, 1))
+				require.ErrorIs(t, err, io.EOF)
+			}
+		})
+	}
+}
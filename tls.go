@@ -0,0 +1,151 @@
+package zysms
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// errProxyTls PROXY 头在 TLS 握手之前, TLS 监听不能读取
+var errProxyTls = errors.New("ProxyProtocol 不支持 TLS 监听")
+
+// ListenTlsConfig 使用 config 监听 TLS, 设置 ClientAuth 及 ClientCAs 时为双向认证,
+// 客户端证书对应的账号见 SMS.CertAccount, 可通过 Listener.SetTLSConfig 更换配置
+func (s *SMS) ListenTlsConfig(addr string, config *tls.Config) (*Listener, error) {
+	if s.ProxyProtocol {
+		return nil, errProxyTls
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	tc := &atomic.Pointer[tls.Config]{}
+	tc.Store(config)
+	// 每次握手取当前配置, 更换证书不需要重启监听
+	ln = tls.NewListener(ln, &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return tc.Load(), nil
+		},
+	})
+	l, err := newListener(ln, s)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	l.tlsConfig = tc
+	tryGO(l.serve)
+	return l, nil
+}
+
+// SetTLSConfig 更换 TLS 配置, 之后的握手使用新配置, 已建立的连接不受影响
+func (l *Listener) SetTLSConfig(config *tls.Config) {
+	if l.tlsConfig != nil {
+		l.tlsConfig.Store(config)
+	}
+}
+
+// dialTLS 使用 SMS.TLSConfig, 为空时使用默认配置并校验服务端证书, ServerName 为空时取 addr 中的主机名
+func (s *SMS) dialTLS(addr string, timeout time.Duration) (net.Conn, error) {
+	config := s.TLSConfig
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	return tls.DialWithDialer(dialer, "tcp", addr, config)
+}
+
+// PeerCertificate 对端已校验的证书, 非 TLS 或对端未提供证书时为 nil
+func (c *sms_conn) PeerCertificate() *x509.Certificate {
+	conn := c.Conn
+	tc, ok := conn.(*tls.Conn)
+	for !ok {
+		// 识别协议时包装的连接
+		w, wrapped := conn.(interface{ NetConn() net.Conn })
+		if !wrapped {
+			return nil
+		}
+		conn = w.NetConn()
+		tc, ok = conn.(*tls.Conn)
+	}
+	state := tc.ConnectionState()
+	if len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return state.PeerCertificates[0]
+}
+
+// certAllowed 对端提供了已校验的客户端证书时, 证书对应的账号须与登录账号一致
+func (c *sms_conn) certAllowed(id string) bool {
+	cert := c.PeerCertificate()
+	if cert == nil {
+		return true
+	}
+	if f := c.parent.CertAccount; f != nil {
+		return f(cert) == id
+	}
+	return cert.Subject.CommonName == id
+}
+
+// CertReloader 从文件加载证书, Reload 后新的握手使用新证书.
+// 用于 tls.Config 的 GetCertificate(服务端) 或 GetClientCertificate(客户端)
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+	// modTime 已加载的证书文件修改时间(纳秒)
+	modTime atomic.Int64
+}
+
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 重新加载证书, 失败时保留原证书
+func (r *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert.Store(&cert)
+	if fi, err := os.Stat(r.certFile); err == nil {
+		r.modTime.Store(fi.ModTime().UnixNano())
+	}
+	return nil
+}
+
+// Watch 每隔 interval 检查证书文件修改时间, 变化时重新加载, ctx 结束时返回
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			fi, err := os.Stat(r.certFile)
+			if err != nil || fi.ModTime().UnixNano() == r.modTime.Load() {
+				continue
+			}
+			if err = r.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
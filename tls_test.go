@@ -0,0 +1,164 @@
+package zysms
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/codec"
+)
+
+// testCA 测试用的 CA, issue 签发 127.0.0.1 可用的证书
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue 返回 PEM 格式的证书及私钥
+func (ca *testCA) issue(t *testing.T, cn string, serial int64) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	b, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
+}
+
+func (ca *testCA) keyPair(t *testing.T, cn string) tls.Certificate {
+	t.Helper()
+	cert, err := tls.X509KeyPair(ca.issue(t, cn, 2))
+	require.NoError(t, err)
+	return cert
+}
+
+func TestTlsPeerVerification(t *testing.T) {
+	ca := newTestCA(t)
+	s := New(codec.CMPP30)
+	s.Authenticator = testAuth
+	cert, key := ca.issue(t, "server", 2)
+	l, err := s.ListenTls("127.0.0.1:0", cert, key)
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownNow(s) })
+
+	// 校验服务端证书
+	c := New(codec.CMPP30)
+	c.TLSConfig = &tls.Config{RootCAs: ca.pool}
+	_, err = c.Dial(l.Addr().String(), testUid, testPwd, time.Second, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownNow(c) })
+
+	// 默认配置不信任测试 CA
+	_, err = New(codec.CMPP30).Dial(l.Addr().String(), testUid, testPwd, time.Second, map[string]string{"tls": "1"})
+	var e *tls.CertificateVerificationError
+	require.ErrorAs(t, err, &e)
+}
+
+func TestTlsCertAccount(t *testing.T) {
+	ca := newTestCA(t)
+	// AUTO 识别协议时连接被包装, 仍能取得客户端证书
+	s := New(codec.AUTO)
+	s.Authenticator = testAuth
+	l, err := s.ListenTlsConfig("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{ca.keyPair(t, "server")},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    ca.pool,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownNow(s) })
+
+	dial := func(cn string) error {
+		c := New(codec.CMPP30)
+		c.TLSConfig = &tls.Config{RootCAs: ca.pool, Certificates: []tls.Certificate{ca.keyPair(t, cn)}}
+		t.Cleanup(func() { shutdownNow(c) })
+		_, err := c.Dial(l.Addr().String(), testUid, testPwd, time.Second, nil)
+		return err
+	}
+	require.NoError(t, dial(testUid))
+	in := serverConn(t, l)
+	require.NotNil(t, in.PeerCertificate())
+	require.Equal(t, testUid, in.PeerCertificate().Subject.CommonName)
+
+	// 证书与登录账号不一致
+	require.Error(t, dial("900002"))
+}
+
+func TestCertReloader(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	write := func(serial int64) {
+		cert, key := ca.issue(t, "server", serial)
+		require.NoError(t, os.WriteFile(certFile, cert, 0o600))
+		require.NoError(t, os.WriteFile(keyFile, key, 0o600))
+	}
+	write(10)
+	r, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	s := New(codec.CMPP30)
+	l, err := s.ListenTlsConfig("127.0.0.1:0", &tls.Config{GetCertificate: r.GetCertificate})
+	require.NoError(t, err)
+	t.Cleanup(func() { shutdownNow(s) })
+
+	serial := func() int64 {
+		c, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{RootCAs: ca.pool})
+		require.NoError(t, err)
+		defer c.Close()
+		return c.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+	require.Equal(t, int64(10), serial())
+
+	// 重新加载后新的握手使用新证书, 加载失败时保留原证书
+	write(11)
+	require.NoError(t, r.Reload())
+	require.Equal(t, int64(11), serial())
+	require.NoError(t, os.WriteFile(keyFile, []byte("bad"), 0o600))
+	require.Error(t, r.Reload())
+	require.Equal(t, int64(11), serial())
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter 令牌桶限速,每秒补充 tps 个令牌,最多累积 burst 个
+type Limiter struct {
+	mu     sync.Mutex
+	tps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// 创建, burst 小于1时取 tps
+func NewLimiter(tps, burst int) *Limiter {
+	l := &Limiter{}
+	l.SetRate(tps, burst)
+	l.tokens = l.burst
+	return l
+}
+
+// 修改速率
+func (l *Limiter) SetRate(tps, burst int) {
+	if burst < 1 {
+		burst = tps
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.tps = float64(tps)
+	l.burst = float64(burst)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+func (l *Limiter) refill(now time.Time) {
+	if !l.last.IsZero() && l.tps > 0 {
+		l.tokens += now.Sub(l.last).Seconds() * l.tps
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// 尝试取一个令牌,不等待
+func (l *Limiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	if l.tokens >= 1 {
+		l.tokens--
+		return true
+	}
+	return false
+}
+
+// 预占一个令牌,返回需要等待的时间,放弃时调用 Cancel 归还
+func (l *Limiter) Reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.tokens--
+	if l.tokens >= 0 || l.tps <= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.tps * float64(time.Second))
+}
+
+// 归还 Reserve 预占的令牌
+func (l *Limiter) Cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// 取一个令牌,不足时等待补充,ctx 结束时归还预占的令牌
+func (l *Limiter) Wait(ctx context.Context) error {
+	wait := l.Reserve()
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.Cancel()
+		return ctx.Err()
+	}
+}
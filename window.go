@@ -0,0 +1,108 @@
+package zysms
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/sgip"
+	"github.com/zhiyin2021/zysms/smgp"
+	"github.com/zhiyin2021/zysms/smpp"
+	"github.com/zhiyin2021/zysms/smserror"
+	"github.com/zhiyin2021/zysms/utils/logger"
+)
+
+// window 滑动窗口,限制未收到响应的请求数量
+type window struct {
+	slots   chan struct{}
+	mu      sync.Mutex
+	items   map[int32]*time.Timer
+	timeout time.Duration
+	reject  bool
+}
+
+func newWindow(size int, timeout time.Duration, reject bool) *window {
+	return &window{
+		slots:   make(chan struct{}, size),
+		items:   map[int32]*time.Timer{},
+		timeout: timeout,
+		reject:  reject,
+	}
+}
+
+// acquire 占用一个窗口位置,窗口已满时阻塞或直接拒绝
+func (w *window) acquire(ctx context.Context, done <-chan struct{}, seq int32) error {
+	if w.reject {
+		select {
+		case w.slots <- struct{}{}:
+		default:
+			return smserror.ErrWindowFull
+		}
+	} else {
+		select {
+		case w.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-done:
+			return smserror.ErrConnIsClosed
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timeout > 0 {
+		// 响应丢失时超时释放,避免窗口被占满卡死
+		w.items[seq] = time.AfterFunc(w.timeout, func() {
+			if w.release(seq) {
+				logger.Warnf("window slot %d released by timeout", seq)
+			}
+		})
+	} else {
+		w.items[seq] = nil
+	}
+	return nil
+}
+
+// release 收到响应后释放窗口位置
+func (w *window) release(seq int32) bool {
+	w.mu.Lock()
+	t, ok := w.items[seq]
+	if ok {
+		delete(w.items, seq)
+	}
+	w.mu.Unlock()
+	if !ok {
+		return false
+	}
+	if t != nil {
+		t.Stop()
+	}
+	<-w.slots
+	return true
+}
+
+func (w *window) len() int {
+	return len(w.slots)
+}
+
+// windowed 需要占用窗口的请求,心跳、登录、退出及无响应的报文除外
+func windowed(pdu PDU) bool {
+	if isResponse(pdu) {
+		return false
+	}
+	switch pdu.(type) {
+	case *cmpp.ActiveTestReq, *cmpp.ConnReq, *cmpp.TerminateReq,
+		*smgp.ActiveTestReq, *smgp.LoginReq, *smgp.ExitReq,
+		*sgip.BindReq, *sgip.UnbindReq,
+		*smpp.EnquireLink, *smpp.BindRequest, *smpp.Unbind, *smpp.Outbind, *smpp.AlertNotification:
+		return false
+	}
+	return true
+}
+
+// releaseWindow 在协议 recv 中调用,响应到达时释放对应的窗口位置
+func (c *sms_conn) releaseWindow(pdu PDU) {
+	if w := c.window; w != nil && isResponse(pdu) {
+		w.release(pdu.GetSequenceNumber())
+	}
+}
@@ -0,0 +1,112 @@
+package zysms
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zhiyin2021/zysms/cmpp"
+	"github.com/zhiyin2021/zysms/codec"
+	"github.com/zhiyin2021/zysms/smserror"
+)
+
+func TestWindow(t *testing.T) {
+	submit := func() codec.PDU { return cmpp.NewSubmitReq(cmpp.V30) }
+	tests := []struct {
+		name    string
+		size    int
+		timeout time.Duration
+		reject  bool
+		run     func(t *testing.T, c *sms_conn, peer net.Conn, reqs <-chan codec.PDU)
+	}{
+		{
+			name: "release by seq",
+			size: 2,
+			run: func(t *testing.T, c *sms_conn, peer net.Conn, reqs <-chan codec.PDU) {
+				_, err := c.SendAsync(submit())
+				require.NoError(t, err)
+				f2, err := c.SendAsync(submit())
+				require.NoError(t, err)
+				require.Equal(t, 2, c.InFlight())
+				<-reqs
+				writePDU(t, peer, (<-reqs).GetResponse())
+				_, err = f2.Result()
+				require.NoError(t, err)
+				require.Equal(t, 1, c.InFlight())
+				// 未知序列号的响应不释放
+				require.False(t, c.window.release(-1))
+				require.Equal(t, 1, c.InFlight())
+			},
+		},
+		{
+			name:   "reject when full",
+			size:   1,
+			reject: true,
+			run: func(t *testing.T, c *sms_conn, peer net.Conn, reqs <-chan codec.PDU) {
+				_, err := c.SendAsync(submit())
+				require.NoError(t, err)
+				_, err = c.SendAsync(submit())
+				require.ErrorIs(t, err, smserror.ErrWindowFull)
+				require.Equal(t, 1, c.InFlight())
+				// 心跳不占用窗口
+				require.NoError(t, c.SendPDU(cmpp.NewActiveTestReq(cmpp.V30)))
+			},
+		},
+		{
+			name: "block until ctx done",
+			size: 1,
+			run: func(t *testing.T, c *sms_conn, peer net.Conn, reqs <-chan codec.PDU) {
+				f, err := c.SendAsync(submit())
+				require.NoError(t, err)
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+				_, err = c.Request(ctx, submit())
+				require.ErrorIs(t, err, context.DeadlineExceeded)
+
+				writePDU(t, peer, (<-reqs).GetResponse())
+				_, err = f.Result()
+				require.NoError(t, err)
+				require.Eventually(t, func() bool { return c.InFlight() == 0 }, time.Second, 5*time.Millisecond)
+				_, err = c.SendAsync(submit())
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:    "slot timeout",
+			size:    1,
+			timeout: 30 * time.Millisecond,
+			reject:  true,
+			run: func(t *testing.T, c *sms_conn, peer net.Conn, reqs <-chan codec.PDU) {
+				_, err := c.SendAsync(submit())
+				require.NoError(t, err)
+				require.Eventually(t, func() bool { return c.InFlight() == 0 }, time.Second, 5*time.Millisecond)
+				_, err = c.SendAsync(submit())
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:   "late response after request timeout",
+			size:   1,
+			reject: true,
+			run: func(t *testing.T, c *sms_conn, peer net.Conn, reqs <-chan codec.PDU) {
+				c.requestTimeout = 20 * time.Millisecond
+				_, err := c.Request(context.Background(), submit())
+				require.ErrorIs(t, err, smserror.ErrRequestTimeout)
+				// 请求已超时, 窗口位置等待响应释放
+				require.Equal(t, 1, c.InFlight())
+				writePDU(t, peer, (<-reqs).GetResponse())
+				require.Eventually(t, func() bool { return c.InFlight() == 0 }, time.Second, 5*time.Millisecond)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, peer := testPipe(t, codec.CMPP30)
+			c.window = newWindow(tt.size, tt.timeout, tt.reject)
+			reqs := readPDUs(peer, 4)
+			tt.run(t, c, peer, reqs)
+		})
+	}
+}